@@ -13,11 +13,19 @@ overwrites:
 containerd:
 {{ toYaml .Values.containerd | indent 2 }}
 {{- end }}
+{{- if .Values.podWebhook }}
+podWebhook:
+{{ toYaml .Values.podWebhook | indent 2 }}
+{{- end }}
 {{- end -}}
 
 {{- define "configmap" -}}
 {{- end }}
 
+{{- define "externalconfigmap" -}}
+{{- if and .Values.configMap .Values.configMap.name }}true{{- end }}
+{{- end -}}
+
 {{- define "leaderelectionid" -}}
 extension-image-rewriter-leader-election
 {{- end -}}
@@ -36,6 +44,7 @@ extension-image-rewriter-leader-election
 
 {{- define "disabledwebhooks" }}
 {{- $disabledWebhooks := list }}
+{{- if not (include "externalconfigmap" .) }}
 {{- if not .Values.overwrites }}
 {{- $disabledWebhooks = append $disabledWebhooks "pod-image-rewriter" }}
 {{- $disabledWebhooks = append $disabledWebhooks "osc-image-rewriter" }}
@@ -43,5 +52,6 @@ extension-image-rewriter-leader-election
 {{- if not .Values.containerd }}
 {{- $disabledWebhooks = append $disabledWebhooks "osc-containerd" }}
 {{- end }}
+{{- end }}
 {{- join "," $disabledWebhooks -}}
 {{- end -}}
@@ -59,6 +59,8 @@ func NewServiceControllerCommand() *cobra.Command {
 	verflag.AddFlags(cmd.Flags())
 	options.optionAggregator.AddFlags(cmd.Flags())
 
+	cmd.AddCommand(NewPreviewCommand(), NewMutateCommand(), NewLintCommand())
+
 	return cmd
 }
 
@@ -83,6 +85,10 @@ func (o *Options) run(ctx context.Context) error {
 		return fmt.Errorf("could not instantiate controller-manager: %w", err)
 	}
 
+	if err := o.extensionOptions.Completed().Load(ctx, mgr.GetAPIReader()); err != nil {
+		return fmt.Errorf("could not load image rewriter configuration: %w", err)
+	}
+
 	scheme := mgr.GetScheme()
 	if err := extensionscontroller.AddToScheme(scheme); err != nil {
 		return fmt.Errorf("could not update manager scheme: %w", err)
@@ -104,6 +110,10 @@ func (o *Options) run(ctx context.Context) error {
 		return fmt.Errorf("could not add controllers to manager: %w", err)
 	}
 
+	if err := o.extensionOptions.Completed().AddReloaderToManager(log, mgr); err != nil {
+		return err
+	}
+
 	if err := mgr.AddReadyzCheck("informer-sync", gardenerhealthz.NewCacheSyncHealthz(mgr.GetCache())); err != nil {
 		return fmt.Errorf("could not add ready check for informers: %w", err)
 	}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	extensioncmd "github.com/gardener/gardener-extension-image-rewriter/pkg/cmd"
+)
+
+// NewLintCommand creates a new command that validates the configuration and reports rules which likely don't behave
+// as intended.
+func NewLintCommand() *cobra.Command {
+	var configLocations []string
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Validate the configuration and report shadowed rules, overlapping prefixes and duplicate containerd hosts.",
+		Long: `Validate the configuration and report shadowed rules, overlapping prefixes and duplicate containerd hosts.
+The command fails if the configuration is invalid or if there are warnings.`,
+		Example: "  image-rewriter lint --config config.yaml --config conf.d",
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if len(configLocations) == 0 {
+				return errors.New("--config is required")
+			}
+			cmd.SilenceUsage = true
+
+			errs, warnings, err := extensioncmd.LintFiles(configLocations)
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+
+			for _, err := range errs {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "error: %s\n", err); err != nil {
+					return err
+				}
+			}
+			for _, warning := range warnings {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "warning: %s\n", warning); err != nil {
+					return err
+				}
+			}
+
+			if len(errs) > 0 || len(warnings) > 0 {
+				return fmt.Errorf("found %d errors and %d warnings", len(errs), len(warnings))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&configLocations, "config", nil, "Path to an image rewriter configuration file or to a directory containing configuration files, can be given multiple times")
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/mutate"
+)
+
+// NewMutateCommand creates a new command that runs the webhook mutators against manifests without a seed.
+func NewMutateCommand() *cobra.Command {
+	var (
+		options = &offlineOptions{}
+		diff    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mutate [file...]",
+		Short: "Mutate OperatingSystemConfig, Pod and workload manifests like the webhooks for a provider and region.",
+		Long: `Mutate OperatingSystemConfig, Pod and workload manifests like the webhooks for a provider and region.
+The manifests are read from the given files or from stdin ('-'). The webhooks run against a fake Cluster of the
+provider and region. Pods and workload resources are only mutated if the pod webhook selects them: the namespace
+selector is evaluated against the labels of the namespaces in the manifests which precede them, namespaces which are
+not contained are only known by their name. Warnings are printed to stderr.`,
+		Example: `  image-rewriter mutate --config config.yaml --provider aws --region eu-west-1 osc.yaml
+  image-rewriter mutate --config config.yaml --provider aws --region eu-west-1 --diff < pod.yaml`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			config, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+
+			// The mutators log every rewrite, which must not end up in the printed manifests.
+			ctx := logf.IntoContext(cmd.Context(), logr.Discard())
+			mutator := mutate.NewMutator(config, options.provider, options.region)
+
+			for i, name := range args {
+				data, err := readManifests(cmd.InOrStdin(), name)
+				if err != nil {
+					return err
+				}
+
+				original, mutated, err := mutator.MutateManifests(ctx, data)
+				if err != nil {
+					return fmt.Errorf("failed to mutate %s: %w", name, err)
+				}
+
+				if diff {
+					out, err := mutate.Diff(name, original, mutated)
+					if err != nil {
+						return err
+					}
+					if _, err := io.WriteString(cmd.OutOrStdout(), out); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if i > 0 {
+					mutated = append([]byte("---\n"), mutated...)
+				}
+				if _, err := cmd.OutOrStdout().Write(mutated); err != nil {
+					return err
+				}
+			}
+
+			for _, warning := range mutator.Warnings() {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&diff, "diff", false, "Print a unified diff of the manifests instead of the mutated manifests")
+
+	return cmd
+}
+
+func readManifests(stdin io.Reader, name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	extensioncmd "github.com/gardener/gardener-extension-image-rewriter/pkg/cmd"
+)
+
+// offlineOptions are the options of the commands which apply the configuration without a seed.
+type offlineOptions struct {
+	configLocations []string
+	provider        string
+	region          string
+}
+
+func (o *offlineOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&o.configLocations, "config", nil, "Path to an image rewriter configuration file or to a directory containing configuration files, can be given multiple times")
+	fs.StringVar(&o.provider, "provider", "", "Provider type of the shoot, e.g. aws")
+	fs.StringVar(&o.region, "region", "", "Region of the shoot")
+}
+
+func (o *offlineOptions) validate() error {
+	if len(o.configLocations) == 0 {
+		return errors.New("--config is required")
+	}
+	if o.provider == "" {
+		return errors.New("--provider is required")
+	}
+	return nil
+}
+
+func (o *offlineOptions) loadConfiguration() (*v1alpha1.Configuration, error) {
+	config, err := extensioncmd.LoadFiles(o.configLocations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return config, nil
+}
@@ -71,9 +71,12 @@ func NewOptions() *Options {
 			Namespace:            os.Getenv("LEADER_ELECTION_NAMESPACE"),
 		},
 		controllerSwitches: cmd.ControllerSwitches(),
-		extensionOptions:   &cmd.ExtensionOptions{},
-		reconcileOptions:   &controllercmd.ReconcilerOptions{},
-		webhookOptions:     webhookOptions,
+		extensionOptions: &cmd.ExtensionOptions{
+			// This is a default value.
+			ConfigMapNamespace: os.Getenv("LEADER_ELECTION_NAMESPACE"),
+		},
+		reconcileOptions: &controllercmd.ReconcilerOptions{},
+		webhookOptions:   webhookOptions,
 	}
 
 	options.optionAggregator = controllercmd.NewOptionAggregator(
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/mutate"
+)
+
+// NewPreviewCommand creates a new command that previews the rewrites of the configuration for a list of images.
+func NewPreviewCommand() *cobra.Command {
+	options := &offlineOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "preview [image...]",
+		Short: "Preview the image rewrites and containerd hosts.toml files for a provider and region.",
+		Long: `Preview the image rewrites and containerd hosts.toml files for a provider and region.
+The images are passed as arguments or read from stdin, one image per line.`,
+		Example: "  image-rewriter preview --config config.yaml --provider aws --region eu-west-1 < images.txt",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			config, err := options.loadConfiguration()
+			if err != nil {
+				return err
+			}
+
+			images := args
+			if len(images) == 0 {
+				if images, err = mutate.ReadImages(cmd.InOrStdin()); err != nil {
+					return fmt.Errorf("failed to read images: %w", err)
+				}
+			}
+
+			return mutate.WritePreview(cmd.OutOrStdout(), config, options.provider, options.region, images)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
@@ -15,4 +15,4 @@ PROJECT_ROOT=$(dirname $0)/..
 
 kube::codegen::gen_helpers \
   --boilerplate "${PROJECT_ROOT}/hack/LICENSE_BOILERPLATE.txt" \
-  "${PROJECT_ROOT}/pkg/apis/config"
+  "${PROJECT_ROOT}/pkg/apis"
@@ -17,29 +17,140 @@ type Configuration struct {
 	// ContainerdConfiguration contains the containerd configuration for the image rewriter.
 	// +optional
 	Containerd []ContainerdConfiguration `json:"containerd,omitempty"`
+	// Matching is the mode in which the overwrites are matched against images, either 'FirstMatch' or 'LongestPrefix'.
+	// Defaults to 'FirstMatch'.
+	// +optional
+	Matching MatchingMode `json:"matching,omitempty"`
 	// Overwrites configure the source and target images that should be replaced.
 	// +optional
 	Overwrites []ImageOverwrite `json:"overwrites,omitempty"`
+	// PodWebhook contains the configuration of the webhook which rewrites the images of pods in the shoot cluster.
+	// +optional
+	PodWebhook *PodWebhookConfiguration `json:"podWebhook,omitempty"`
+}
+
+// MatchingMode is the mode in which the overwrites are matched against images.
+type MatchingMode string
+
+const (
+	// MatchingModeFirstMatch matches the overwrites in the configured order. The first overwrite which matches an image
+	// and has a target for the shoot's provider and region wins.
+	MatchingModeFirstMatch MatchingMode = "FirstMatch"
+	// MatchingModeLongestPrefix matches the most specific overwrite regardless of the configured order. 'image' sources
+	// take precedence over 'prefix' sources, longer prefixes over shorter ones and 'regex' sources come last. Overwrites
+	// of the same precedence are matched in the configured order.
+	MatchingModeLongestPrefix MatchingMode = "LongestPrefix"
+)
+
+// PodWebhookConfiguration contains information about the pod webhook configuration.
+// The webhook records the original images of rewritten pods in the 'image-rewriter.extensions.gardener.cloud/original-images'
+// annotation. Ephemeral containers are not recorded, because the 'pods/ephemeralcontainers' subresource ignores changes of
+// the metadata.
+type PodWebhookConfiguration struct {
+	// NamespaceSelector selects the namespaces of the pods which are rewritten.
+	// If not specified, only pods in the 'kube-system' namespace are rewritten. Namespaces labelled with
+	// 'image-rewriter.extensions.gardener.cloud/skip: true' are always excluded.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ObjectSelector selects the pods which are rewritten. If not specified, all pods in the selected namespaces are rewritten.
+	// For workload resources, it is evaluated against the labels of the resource, not against the labels of its pod template.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// WorkloadKinds are the kinds of workload resources whose pod templates are rewritten in addition to pods.
+	// Supported kinds are 'Deployment', 'DaemonSet', 'StatefulSet', 'Job' and 'CronJob'. If not specified, only pods are rewritten.
+	// The pod template of a 'Job' is immutable, hence it is only rewritten when the 'Job' is created.
+	// +optional
+	WorkloadKinds []string `json:"workloadKinds,omitempty"`
 }
 
 // ContainerdConfiguration contains information about a containerd upstream configuration.
 type ContainerdConfiguration struct {
-	// Upstream is the upstream name of the registry.
+	// Upstream is the upstream name of the registry. The upstream `_default` applies to all registries without an
+	// upstream configuration of their own.
 	Upstream string `json:"upstream"`
-	// Server is the URL of the upstream registry.
-	Server string `json:"server"`
+	// Server is the URL of the upstream registry. It must not be set for the upstream `_default`, containerd falls back
+	// to the registry of the pulled image instead.
+	// +optional
+	Server string `json:"server,omitempty"`
 	// Hosts are the containerd hosts separated by provider and regions.
 	Hosts []ContainerdHostConfig `json:"hosts"`
+	// MergeStrategy defines how the hosts are merged with a registry configuration for the same upstream which another
+	// extension, e.g. the registry cache, added to the OperatingSystemConfig of reconciled nodes. Defaults to `Skip`.
+	// +optional
+	MergeStrategy MergeStrategy `json:"mergeStrategy,omitempty"`
 }
 
+// MergeStrategy defines how the hosts of an upstream are merged with an existing registry configuration.
+type MergeStrategy string
+
+const (
+	// MergeStrategySkip leaves the existing registry configuration unchanged.
+	MergeStrategySkip MergeStrategy = "Skip"
+	// MergeStrategyAppend adds the hosts after the existing hosts, the existing server is kept.
+	MergeStrategyAppend MergeStrategy = "Append"
+	// MergeStrategyPrepend adds the hosts before the existing hosts, the existing server is kept.
+	MergeStrategyPrepend MergeStrategy = "Prepend"
+	// MergeStrategyReplace replaces the existing registry configuration.
+	MergeStrategyReplace MergeStrategy = "Replace"
+)
+
+// DefaultUpstream is the upstream whose hosts apply to all registries without an upstream configuration of their own.
+const DefaultUpstream = "_default"
+
 // ContainerdHostConfig contains information about a containerd host configuration.
 type ContainerdHostConfig struct {
-	URL string `json:"url"`
-	// Provider is the name of the provider for which this target is applicable.
+	// URL is the URL of the host. Either URL or URLs must be set.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// URLs are the URLs of several hosts which containerd tries in the given order before it falls back to the upstream
+	// server, e.g. a regional mirror followed by a cross-region mirror. Either URL or URLs must be set.
+	// +optional
+	URLs []string `json:"urls,omitempty"`
+	// Provider is the name of the provider for which this target is applicable. It can be a glob pattern, e.g. `aws*`,
+	// the exact provider takes precedence over patterns.
 	Provider string `json:"provider"`
 	// Regions are the regions where the target image is located. If not specified, any shoot region will match this host config.
+	// Regions can be glob patterns, e.g. `eu-*`. An exact region takes precedence over a matching pattern, which takes
+	// precedence over a host config without regions.
 	// +optional
 	Regions []string `json:"regions,omitempty"`
+	// CA is the PEM encoded CA bundle which is used to verify the certificates of the hosts.
+	// +optional
+	CA string `json:"ca,omitempty"`
+	// Client is the client certificate which is presented to the hosts. It is only applied when nodes are provisioned.
+	// +optional
+	Client *ContainerdClientCertificate `json:"client,omitempty"`
+	// SkipVerify disables the verification of the certificates of the hosts. It is only applied when nodes are
+	// provisioned.
+	// +optional
+	SkipVerify *bool `json:"skipVerify,omitempty"`
+	// Capabilities are the operations which containerd performs with the hosts. Defaults to `pull` and `resolve`.
+	// +optional
+	Capabilities []ContainerdCapability `json:"capabilities,omitempty"`
+	// Header are static headers which are sent with every request to the hosts. It is only applied when nodes are
+	// provisioned.
+	// +optional
+	Header map[string][]string `json:"header,omitempty"`
+}
+
+// ContainerdCapability is an operation which containerd performs with a host.
+type ContainerdCapability string
+
+const (
+	// ContainerdCapabilityPull allows fetching manifests and blobs by digest.
+	ContainerdCapabilityPull ContainerdCapability = "pull"
+	// ContainerdCapabilityResolve allows resolving tags to digests.
+	ContainerdCapabilityResolve ContainerdCapability = "resolve"
+	// ContainerdCapabilityPush allows pushing images.
+	ContainerdCapabilityPush ContainerdCapability = "push"
+)
+
+// ContainerdClientCertificate contains a client certificate for a containerd host.
+type ContainerdClientCertificate struct {
+	// Cert is the PEM encoded client certificate.
+	Cert string `json:"cert"`
+	// Key is the PEM encoded private key of the client certificate.
+	Key string `json:"key"`
 }
 
 // ImageOverwrite contains information about an image overwrite configuration.
@@ -53,9 +164,12 @@ type ImageOverwrite struct {
 // TargetConfiguration contains information about the target image configuration.
 type TargetConfiguration struct {
 	Image `json:",inline"`
-	// Provider is the name of the provider for which this target is applicable.
+	// Provider is the name of the provider for which this target is applicable. It can be a glob pattern, e.g. `aws*`,
+	// the exact provider takes precedence over patterns.
 	Provider string `json:"provider"`
 	// Regions are the regions where the target image is located. If not specified, any shoot region will match this target config.
+	// Regions can be glob patterns, e.g. `eu-*`. An exact region takes precedence over a matching pattern, which takes
+	// precedence over a target config without regions.
 	// +optional
 	Regions []string `json:"regions,omitempty"`
 }
@@ -68,4 +182,8 @@ type Image struct {
 	// Prefix is the prefix of the target image to relace the source with.
 	// +optional
 	Prefix *string `json:"prefix,omitempty"`
+	// Regex is a regular expression matching the whole source image. It is only supported for sources.
+	// Targets of a regex source must set 'image' and can refer to capture groups of the expression, e.g. '$1' or '${name}'.
+	// +optional
+	Regex *string `json:"regex,omitempty"`
 }
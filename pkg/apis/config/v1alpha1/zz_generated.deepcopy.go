@@ -10,6 +10,7 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -31,6 +32,11 @@ func (in *Configuration) DeepCopyInto(out *Configuration) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PodWebhook != nil {
+		in, out := &in.PodWebhook, &out.PodWebhook
+		*out = new(PodWebhookConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -52,6 +58,22 @@ func (in *Configuration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerdClientCertificate) DeepCopyInto(out *ContainerdClientCertificate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerdClientCertificate.
+func (in *ContainerdClientCertificate) DeepCopy() *ContainerdClientCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerdClientCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerdConfiguration) DeepCopyInto(out *ContainerdConfiguration) {
 	*out = *in
@@ -78,11 +100,46 @@ func (in *ContainerdConfiguration) DeepCopy() *ContainerdConfiguration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerdHostConfig) DeepCopyInto(out *ContainerdHostConfig) {
 	*out = *in
+	if in.URLs != nil {
+		in, out := &in.URLs, &out.URLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Regions != nil {
 		in, out := &in.Regions, &out.Regions
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Client != nil {
+		in, out := &in.Client, &out.Client
+		*out = new(ContainerdClientCertificate)
+		**out = **in
+	}
+	if in.SkipVerify != nil {
+		in, out := &in.SkipVerify, &out.SkipVerify
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]ContainerdCapability, len(*in))
+		copy(*out, *in)
+	}
+	if in.Header != nil {
+		in, out := &in.Header, &out.Header
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 	return
 }
 
@@ -109,6 +166,11 @@ func (in *Image) DeepCopyInto(out *Image) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Regex != nil {
+		in, out := &in.Regex, &out.Regex
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -146,6 +208,37 @@ func (in *ImageOverwrite) DeepCopy() *ImageOverwrite {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodWebhookConfiguration) DeepCopyInto(out *PodWebhookConfiguration) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WorkloadKinds != nil {
+		in, out := &in.WorkloadKinds, &out.WorkloadKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodWebhookConfiguration.
+func (in *PodWebhookConfiguration) DeepCopy() *PodWebhookConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(PodWebhookConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetConfiguration) DeepCopyInto(out *TargetConfiguration) {
 	*out = *in
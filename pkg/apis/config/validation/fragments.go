@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+// Fragment is a part of the configuration together with the name of its source, e.g. the file it was read from.
+type Fragment struct {
+	// Source is the name of the source of the fragment.
+	Source string
+	// Configuration is the configuration of the fragment.
+	Configuration *v1alpha1.Configuration
+}
+
+// ValidateFragments validates the passed configuration fragments and detects conflicts between them, i.e. the same
+// source with different targets for the same provider and region, the same containerd upstream with different
+// servers or hosts for the same provider and region, different matching modes and multiple pod webhook configurations.
+// The field paths of the errors start with the source of the fragment, e.g. 'fragments[team-a.yaml].overwrites[0]'.
+func ValidateFragments(fragments []Fragment) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for _, fragment := range fragments {
+		allErrs = append(allErrs, validateConfiguration(fragment.Configuration, fragmentPath(fragment.Source))...)
+	}
+
+	allErrs = append(allErrs, validateFragmentConflicts(fragments)...)
+
+	return allErrs
+}
+
+// origin is the location of a configuration entry.
+type origin struct {
+	source string
+	path   *field.Path
+	value  string
+}
+
+func (o origin) String() string {
+	return fmt.Sprintf("%q in %s", o.value, o.path)
+}
+
+func validateFragmentConflicts(fragments []Fragment) field.ErrorList {
+	var (
+		allErrs = field.ErrorList{}
+
+		overwriteTargets = map[string]origin{}
+		upstreamServers  = map[string]origin{}
+		upstreamHosts    = map[string]origin{}
+		podWebhook       *origin
+		matching         *origin
+	)
+
+	// conflicts records the value at the given key and returns the origin of a different value of another fragment.
+	conflicts := func(entries map[string]origin, key string, current origin) (origin, bool) {
+		previous, exists := entries[key]
+		if !exists {
+			entries[key] = current
+			return origin{}, false
+		}
+		return previous, previous.source != current.source && previous.value != current.value
+	}
+
+	for _, fragment := range fragments {
+		fldPath := fragmentPath(fragment.Source)
+
+		for i, overwrite := range fragment.Configuration.Overwrites {
+			source := sourceKey(overwrite.Source)
+			if source == "" {
+				continue
+			}
+
+			for j, target := range overwrite.Targets {
+				fldTarget := fldPath.Child("overwrites").Index(i).Child("targets").Index(j)
+				current := origin{source: fragment.Source, path: fldTarget, value: targetValue(target.Image)}
+
+				for _, region := range regionsOrAny(target.Regions) {
+					if previous, ok := conflicts(overwriteTargets, fmt.Sprintf("%s|%s|%s", source, target.Provider, region), current); ok {
+						allErrs = append(allErrs, field.Invalid(fldTarget, current.value,
+							fmt.Sprintf("conflicts with target %s for provider %q and %s", previous, target.Provider, regionDescription(region))))
+					}
+				}
+			}
+		}
+
+		for i, containerdConfig := range fragment.Configuration.Containerd {
+			fldContainerd := fldPath.Child("containerd").Index(i)
+
+			current := origin{source: fragment.Source, path: fldContainerd.Child("server"), value: containerdConfig.Server}
+			if previous, ok := conflicts(upstreamServers, containerdConfig.Upstream, current); ok {
+				allErrs = append(allErrs, field.Invalid(current.path, current.value,
+					fmt.Sprintf("conflicts with server %s for upstream %q", previous, containerdConfig.Upstream)))
+			}
+
+			for j, host := range containerdConfig.Hosts {
+				fldHost := fldContainerd.Child("hosts").Index(j)
+				current := origin{source: fragment.Source, path: fldHost.Child("url"), value: host.URL}
+				if len(host.URLs) > 0 {
+					current = origin{source: fragment.Source, path: fldHost.Child("urls"), value: strings.Join(host.URLs, ", ")}
+				}
+
+				for _, region := range regionsOrAny(host.Regions) {
+					if previous, ok := conflicts(upstreamHosts, fmt.Sprintf("%s|%s|%s", containerdConfig.Upstream, host.Provider, region), current); ok {
+						allErrs = append(allErrs, field.Invalid(current.path, current.value,
+							fmt.Sprintf("conflicts with host %s for upstream %q, provider %q and %s", previous, containerdConfig.Upstream, host.Provider, regionDescription(region))))
+					}
+				}
+			}
+		}
+
+		if fragment.Configuration.Matching != "" {
+			current := origin{source: fragment.Source, path: fldPath.Child("matching"), value: string(fragment.Configuration.Matching)}
+			if matching == nil {
+				matching = &current
+			} else if matching.value != current.value {
+				allErrs = append(allErrs, field.Invalid(current.path, current.value, fmt.Sprintf("conflicts with matching mode %s", matching)))
+			}
+		}
+
+		if fragment.Configuration.PodWebhook != nil {
+			fldPodWebhook := fldPath.Child("podWebhook")
+			if podWebhook != nil {
+				allErrs = append(allErrs, field.Forbidden(fldPodWebhook, fmt.Sprintf("pod webhook is already configured in %s", podWebhook.path)))
+			} else {
+				podWebhook = &origin{source: fragment.Source, path: fldPodWebhook}
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func fragmentPath(source string) *field.Path {
+	return field.NewPath("fragments").Key(source)
+}
+
+// sourceKey returns the normalised source of an overwrite, sources which match the same images have the same key.
+func sourceKey(source v1alpha1.Image) string {
+	switch {
+	case source.Prefix != nil:
+		return "prefix:" + image.NormalizePrefix(*source.Prefix)
+	case source.Image != nil:
+		if reference, err := image.ParseNormalizedReference(*source.Image); err == nil {
+			return "image:" + reference.String()
+		}
+		return "image:" + *source.Image
+	case source.Regex != nil:
+		return "regex:" + *source.Regex
+	}
+	return ""
+}
+
+func targetValue(target v1alpha1.Image) string {
+	if target.Prefix != nil {
+		return *target.Prefix
+	}
+	if target.Image != nil {
+		return *target.Image
+	}
+	return ""
+}
+
+// regionsOrAny returns the given regions or a single empty region which stands for any region.
+func regionsOrAny(regions []string) []string {
+	if len(regions) == 0 {
+		return []string{""}
+	}
+	return regions
+}
+
+func regionDescription(region string) string {
+	if region == "" {
+		return "any region"
+	}
+	return fmt.Sprintf("region %q", region)
+}
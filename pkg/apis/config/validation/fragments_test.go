@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+)
+
+var _ = Describe("Fragments", func() {
+	overwrite := func(source, target string, regions ...string) v1alpha1.ImageOverwrite {
+		return v1alpha1.ImageOverwrite{
+			Source:  v1alpha1.Image{Prefix: ptr.To(source)},
+			Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To(target)}, Provider: "aws", Regions: regions}},
+		}
+	}
+
+	upstream := func(server, url string) v1alpha1.ContainerdConfiguration {
+		return v1alpha1.ContainerdConfiguration{
+			Upstream: "docker.io",
+			Server:   server,
+			Hosts:    []v1alpha1.ContainerdHostConfig{{URL: url, Provider: "aws"}},
+		}
+	}
+
+	Describe("#ValidateFragments", func() {
+		It("should allow fragments without conflicts", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{
+					Overwrites: []v1alpha1.ImageOverwrite{overwrite("registry.example.com", "mirror-a.example.com", "eu-west-1")},
+					Containerd: []v1alpha1.ContainerdConfiguration{upstream("https://registry-1.docker.io", "https://mirror.example.com")},
+				}},
+				{Source: "b.yaml", Configuration: &v1alpha1.Configuration{
+					Overwrites: []v1alpha1.ImageOverwrite{
+						overwrite("registry.example.com", "mirror-b.example.com", "us-east-1"),
+						overwrite("other.example.com", "mirror-b.example.com"),
+					},
+					Containerd: []v1alpha1.ContainerdConfiguration{upstream("https://registry-1.docker.io", "https://mirror.example.com")},
+					PodWebhook: &v1alpha1.PodWebhookConfiguration{},
+				}},
+			})).To(BeEmpty())
+		})
+
+		It("should prefix the field paths of invalid fragments with their source", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{Overwrites: []v1alpha1.ImageOverwrite{{Source: v1alpha1.Image{Prefix: ptr.To("foo")}}}}},
+			})).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("fragments[a.yaml].overwrites[0].targets"),
+			}))))
+		})
+
+		It("should report conflicting targets of the same source in different fragments", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{Overwrites: []v1alpha1.ImageOverwrite{overwrite("registry.example.com", "mirror-a.example.com", "eu-west-1")}}},
+				{Source: "b.yaml", Configuration: &v1alpha1.Configuration{Overwrites: []v1alpha1.ImageOverwrite{overwrite("registry.example.com", "mirror-b.example.com", "eu-west-1", "us-east-1")}}},
+			})).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(field.ErrorTypeInvalid),
+				"Field":  Equal("fragments[b.yaml].overwrites[0].targets[0]"),
+				"Detail": Equal(`conflicts with target "mirror-a.example.com" in fragments[a.yaml].overwrites[0].targets[0] for provider "aws" and region "eu-west-1"`),
+			}))))
+		})
+
+		It("should report conflicting containerd upstreams in different fragments", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{Containerd: []v1alpha1.ContainerdConfiguration{upstream("https://registry-1.docker.io", "https://mirror-a.example.com")}}},
+				{Source: "b.yaml", Configuration: &v1alpha1.Configuration{Containerd: []v1alpha1.ContainerdConfiguration{upstream("https://docker.example.com", "https://mirror-b.example.com")}}},
+			})).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("fragments[b.yaml].containerd[0].server"),
+			})), PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(field.ErrorTypeInvalid),
+				"Field":  Equal("fragments[b.yaml].containerd[0].hosts[0].url"),
+				"Detail": ContainSubstring(`conflicts with host "https://mirror-a.example.com" in fragments[a.yaml].containerd[0].hosts[0].url`),
+			}))))
+		})
+
+		It("should report different matching modes in different fragments", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{Matching: v1alpha1.MatchingModeLongestPrefix}},
+				{Source: "b.yaml", Configuration: &v1alpha1.Configuration{}},
+				{Source: "c.yaml", Configuration: &v1alpha1.Configuration{Matching: v1alpha1.MatchingModeFirstMatch}},
+			})).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":   Equal(field.ErrorTypeInvalid),
+				"Field":  Equal("fragments[c.yaml].matching"),
+				"Detail": Equal(`conflicts with matching mode "LongestPrefix" in fragments[a.yaml].matching`),
+			}))))
+		})
+
+		It("should forbid multiple pod webhook configurations", func() {
+			Expect(ValidateFragments([]Fragment{
+				{Source: "a.yaml", Configuration: &v1alpha1.Configuration{PodWebhook: &v1alpha1.PodWebhookConfiguration{}}},
+				{Source: "b.yaml", Configuration: &v1alpha1.Configuration{PodWebhook: &v1alpha1.PodWebhookConfiguration{}}},
+			})).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeForbidden),
+				"Field": Equal("fragments[b.yaml].podWebhook"),
+			}))))
+		})
+	})
+})
@@ -0,0 +1,21 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+)
+
+// ValidateImageRewriterConfig validates the passed shoot specific configuration object.
+func ValidateImageRewriterConfig(config *imagerewriterv1alpha1.ImageRewriterConfig, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, ValidateOverwrites(config.Overwrites, fldPath.Child("overwrites"))...)
+	allErrs = append(allErrs, ValidateContainerd(config.Containerd, fldPath.Child("containerd"))...)
+
+	return allErrs
+}
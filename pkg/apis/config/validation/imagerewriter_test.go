@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+
+	configv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+)
+
+var _ = Describe("ImageRewriterConfig validation", func() {
+	var (
+		fldPath *field.Path
+		config  *v1alpha1.ImageRewriterConfig
+	)
+
+	BeforeEach(func() {
+		fldPath = field.NewPath("providerConfig")
+		config = &v1alpha1.ImageRewriterConfig{
+			Overwrites: []configv1alpha1.ImageOverwrite{
+				{
+					Source: configv1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+					Targets: []configv1alpha1.TargetConfiguration{
+						{
+							Image:    configv1alpha1.Image{Prefix: ptr.To("mirror.example/k8s")},
+							Provider: "local",
+						},
+					},
+				},
+			},
+			Containerd: []configv1alpha1.ContainerdConfiguration{
+				{
+					Upstream: "registry.k8s.io",
+					Server:   "https://registry.k8s.io",
+					Hosts: []configv1alpha1.ContainerdHostConfig{
+						{URL: "https://mirror.example", Provider: "local"},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("#ValidateImageRewriterConfig", func() {
+		It("should allow a valid configuration", func() {
+			Expect(ValidateImageRewriterConfig(config, fldPath)).To(BeEmpty())
+		})
+
+		It("should validate overwrites and containerd configuration", func() {
+			config.Overwrites[0].Targets[0].Provider = ""
+			config.Containerd[0].Hosts[0].URL = ""
+
+			Expect(ValidateImageRewriterConfig(config, fldPath)).To(ConsistOf(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("providerConfig.overwrites[0].targets[0].provider"),
+			})), PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("providerConfig.containerd[0].hosts[0].url"),
+			}))))
+		})
+	})
+})
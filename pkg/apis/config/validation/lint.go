@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/selector"
+)
+
+// Warning is an issue of a valid configuration. Unlike validation errors, warnings don't prevent using the
+// configuration.
+type Warning struct {
+	// Field is the path of the field the warning refers to.
+	Field string
+	// Detail describes the issue.
+	Detail string
+}
+
+func (w Warning) String() string {
+	return w.Field + ": " + w.Detail
+}
+
+// LintConfiguration returns warnings for rules of a valid configuration which likely don't behave as intended:
+//   - targets of overwrites which never apply because an overwrite which is matched earlier matches all of their
+//     images, see image.MatchOrder,
+//   - prefixes which overlap with a broader prefix which is matched later and rewrites the same images differently,
+//   - containerd hosts which are defined more than once for the same upstream, provider and region.
+func LintConfiguration(config *v1alpha1.Configuration) []Warning {
+	var warnings []Warning
+
+	warnings = append(warnings, lintOverwrites(config, field.NewPath("overwrites"))...)
+	warnings = append(warnings, lintContainerd(config.Containerd, field.NewPath("containerd"))...)
+
+	return warnings
+}
+
+// lintSource is the normalised source of an overwrite, exactly one of prefix, image and pattern is set.
+type lintSource struct {
+	prefix  string
+	image   *image.Reference
+	pattern *regexp.Regexp
+	// rawImage is the configured image of an image source, regular expressions are matched against images as they are.
+	rawImage string
+}
+
+func newLintSource(source v1alpha1.Image) lintSource {
+	switch {
+	case source.Prefix != nil:
+		return lintSource{prefix: image.NormalizePrefix(*source.Prefix)}
+	case source.Image != nil:
+		if reference, err := image.ParseNormalizedReference(*source.Image); err == nil {
+			return lintSource{image: &reference, rawImage: *source.Image}
+		}
+	case source.Regex != nil:
+		if pattern, err := image.CompileSourcePattern(*source.Regex); err == nil {
+			return lintSource{pattern: pattern}
+		}
+	}
+	return lintSource{}
+}
+
+// covers returns true if all images which match the other source match this source, too. It is conservative for
+// regular expressions, i.e. it only returns true if the other source is an image.
+func (s lintSource) covers(other lintSource) bool {
+	switch {
+	case s.prefix != "" && other.prefix != "":
+		return strings.HasPrefix(other.prefix, s.prefix)
+	case s.prefix != "" && other.image != nil:
+		return strings.HasPrefix(other.image.String(), s.prefix)
+	case s.image != nil && other.image != nil:
+		// An image source without digest matches the source image regardless of its digest, see image.Configuration.
+		withoutDigest := *other.image
+		withoutDigest.Digest = ""
+		return s.image.String() == other.image.String() || (s.image.Digest == "" && s.image.String() == withoutDigest.String())
+	case s.pattern != nil && other.image != nil:
+		return s.pattern.MatchString(other.rawImage)
+	}
+	return false
+}
+
+func newLintTargets(targets []v1alpha1.TargetConfiguration) *selector.Selector[string] {
+	// The targets are selected the same way image.Configuration selects them.
+	result := &selector.Selector[string]{}
+	for _, target := range targets {
+		result.Add(target.Provider, target.Regions, targetValue(target.Image))
+	}
+	return result
+}
+
+func lintOverwrites(config *v1alpha1.Configuration, fldPath *field.Path) []Warning {
+	var (
+		warnings   []Warning
+		overwrites = config.Overwrites
+		sources    = make([]lintSource, 0, len(overwrites))
+		targets    = make([]*selector.Selector[string], 0, len(overwrites))
+		// order contains the indices of the overwrites in the order they are matched.
+		order = image.MatchOrder(config)
+	)
+
+	for _, overwrite := range overwrites {
+		sources = append(sources, newLintSource(overwrite.Source))
+		targets = append(targets, newLintTargets(overwrite.Targets))
+	}
+
+	for position, j := range order {
+		overwrite := overwrites[j]
+
+		for k, target := range overwrite.Targets {
+			fldTarget := fldPath.Index(j).Child("targets").Index(k)
+
+			// Targets which never apply because an overwrite which is matched earlier matches all of their images.
+			for _, region := range regionsOrAny(target.Regions) {
+				for _, i := range order[:position] {
+					if _, ok := targets[i].Select(target.Provider, region); ok && sources[i].covers(sources[j]) {
+						warnings = append(warnings, Warning{
+							Field: fldTarget.String(),
+							Detail: fmt.Sprintf("never applies for provider %q and %s, %s with source %q matches all of its images first",
+								target.Provider, regionDescription(region), fldPath.Index(i), prefixOrImage(overwrites[i].Source)),
+						})
+						break
+					}
+				}
+			}
+
+			// Prefixes which overlap with a broader prefix which is matched later with a different target.
+			if overwrite.Source.Prefix == nil || target.Prefix == nil {
+				continue
+			}
+			for _, region := range regionsOrAny(target.Regions) {
+				// Only the first broader prefix with a target applies if this prefix didn't exist.
+				for _, i := range order[position+1:] {
+					if overwrites[i].Source.Prefix == nil || sources[i].prefix == sources[j].prefix || !sources[i].covers(sources[j]) {
+						continue
+					}
+					broader, ok := targets[i].Select(target.Provider, region)
+					if !ok {
+						continue
+					}
+
+					if suffix := strings.TrimPrefix(sources[j].prefix, sources[i].prefix); broader+suffix != *target.Prefix {
+						warnings = append(warnings, Warning{
+							Field: fldTarget.String(),
+							Detail: fmt.Sprintf("overlaps with %s with prefix %q which rewrites the same images to %q instead of %q for provider %q and %s",
+								fldPath.Index(i), *overwrites[i].Source.Prefix, broader+suffix, *target.Prefix, target.Provider, regionDescription(region)),
+						})
+					}
+					break
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+func lintContainerd(containerdConfigs []v1alpha1.ContainerdConfiguration, fldPath *field.Path) []Warning {
+	type host struct {
+		path    *field.Path
+		config  int
+		regions []string
+	}
+
+	var (
+		warnings []Warning
+		// hosts maps upstream and provider to the hosts defined for them.
+		hosts = map[string][]host{}
+	)
+
+	for i, containerdConfig := range containerdConfigs {
+		for j, current := range containerdConfig.Hosts {
+			var (
+				key     = containerdConfig.Upstream + "|" + current.Provider
+				fldHost = fldPath.Index(i).Child("hosts").Index(j)
+			)
+
+			for _, previous := range hosts[key] {
+				// Hosts of the same upstream configuration are merged, a host for any region doesn't conflict with hosts
+				// for specific regions. Upstream configurations are applied separately, hence all of them conflict.
+				var conflicting []string
+				switch {
+				case len(previous.regions) == 0 && len(current.Regions) == 0:
+					conflicting = []string{""}
+				case previous.config != i && len(previous.regions) == 0:
+					conflicting = regionsOrAny(current.Regions)
+				case previous.config != i && len(current.Regions) == 0:
+					conflicting = previous.regions
+				default:
+					for _, region := range current.Regions {
+						if slices.Contains(previous.regions, region) {
+							conflicting = append(conflicting, region)
+						}
+					}
+				}
+
+				for _, region := range conflicting {
+					warnings = append(warnings, Warning{
+						Field: fldHost.String(),
+						Detail: fmt.Sprintf("upstream %q is already defined for provider %q and %s in %s, only one of them is used",
+							containerdConfig.Upstream, current.Provider, regionDescription(region), previous.path),
+					})
+				}
+			}
+
+			hosts[key] = append(hosts[key], host{path: fldHost, config: i, regions: current.Regions})
+		}
+	}
+
+	return warnings
+}
+
+func prefixOrImage(source v1alpha1.Image) string {
+	switch {
+	case source.Prefix != nil:
+		return *source.Prefix
+	case source.Image != nil:
+		return *source.Image
+	case source.Regex != nil:
+		return *source.Regex
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validation_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+)
+
+var _ = Describe("Lint", func() {
+	target := func(image v1alpha1.Image, provider string, regions ...string) v1alpha1.TargetConfiguration {
+		return v1alpha1.TargetConfiguration{Image: image, Provider: provider, Regions: regions}
+	}
+	prefix := func(prefix string) v1alpha1.Image { return v1alpha1.Image{Prefix: ptr.To(prefix)} }
+	img := func(image string) v1alpha1.Image { return v1alpha1.Image{Image: ptr.To(image)} }
+
+	Describe("#LintConfiguration", func() {
+		It("should not warn about a configuration without issues", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: img("registry.k8s.io/etcd:3.5"), Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/etcd:3.5"), "aws")}},
+					{Source: prefix("registry.k8s.io/pause"), Targets: []v1alpha1.TargetConfiguration{target(prefix("mirror.example.com/k8s/pause"), "aws")}},
+					{Source: prefix("registry.k8s.io"), Targets: []v1alpha1.TargetConfiguration{target(prefix("mirror.example.com/k8s"), "aws")}},
+				},
+				Containerd: []v1alpha1.ContainerdConfiguration{
+					{Upstream: "docker.io", Server: "https://registry-1.docker.io", Hosts: []v1alpha1.ContainerdHostConfig{
+						{URL: "https://mirror-eu.example.com", Provider: "aws", Regions: []string{"eu-west-1"}},
+						{URL: "https://mirror.example.com", Provider: "aws"},
+					}},
+				},
+			})).To(BeEmpty())
+		})
+
+		It("should warn about targets which never apply because an earlier overwrite matches first", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: prefix("registry.k8s.io"), Targets: []v1alpha1.TargetConfiguration{
+						target(prefix("mirror.example.com/k8s"), "aws"),
+						target(prefix("mirror.example.com/k8s"), "gcp", "europe-west1"),
+					}},
+					{Source: img("registry.k8s.io/etcd:3.5@sha256:0000000000000000000000000000000000000000000000000000000000000000"), Targets: []v1alpha1.TargetConfiguration{
+						target(img("mirror.example.com/etcd:3.5"), "aws", "eu-west-1"),
+						target(img("mirror.example.com/etcd:3.5"), "gcp"),
+					}},
+					{Source: v1alpha1.Image{Regex: ptr.To(`docker\.io/library/.*`)}, Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/$0"), "aws")}},
+					{Source: img("docker.io/library/nginx"), Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/nginx"), "aws")}},
+				},
+			})).To(ConsistOf(
+				Warning{
+					Field:  "overwrites[1].targets[0]",
+					Detail: `never applies for provider "aws" and region "eu-west-1", overwrites[0] with source "registry.k8s.io" matches all of its images first`,
+				},
+				Warning{
+					Field:  "overwrites[3].targets[0]",
+					Detail: `never applies for provider "aws" and any region, overwrites[2] with source "docker\\.io/library/.*" matches all of its images first`,
+				},
+			))
+		})
+
+		It("should take the matching order of the longest prefix mode into account", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Matching: v1alpha1.MatchingModeLongestPrefix,
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: prefix("registry.k8s.io"), Targets: []v1alpha1.TargetConfiguration{target(prefix("mirror.example.com/k8s"), "aws")}},
+					{Source: img("registry.k8s.io/etcd:3.5"), Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/etcd:3.5"), "aws")}},
+					{Source: prefix("registry.k8s.io/pause"), Targets: []v1alpha1.TargetConfiguration{target(prefix("other.example.com/pause"), "aws")}},
+					{Source: prefix("registry.k8s.io"), Targets: []v1alpha1.TargetConfiguration{target(prefix("other.example.com/k8s"), "aws")}},
+				},
+			})).To(ConsistOf(
+				Warning{
+					Field:  "overwrites[2].targets[0]",
+					Detail: `overlaps with overwrites[0] with prefix "registry.k8s.io" which rewrites the same images to "mirror.example.com/k8s/pause" instead of "other.example.com/pause" for provider "aws" and any region`,
+				},
+				Warning{
+					Field:  "overwrites[3].targets[0]",
+					Detail: `never applies for provider "aws" and any region, overwrites[0] with source "registry.k8s.io" matches all of its images first`,
+				},
+			))
+		})
+
+		It("should warn about image sources without digest which match an image with digest first", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: img("nginx:1.27"), Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/nginx:1.27"), "aws")}},
+					{Source: img("nginx:1.27@sha256:0000000000000000000000000000000000000000000000000000000000000000"), Targets: []v1alpha1.TargetConfiguration{target(img("mirror.example.com/nginx:1.27"), "aws")}},
+				},
+			})).To(ConsistOf(HaveField("Field", "overwrites[1].targets[0]")))
+		})
+
+		It("should warn about prefixes which overlap with a later prefix with a different target", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: prefix("registry.k8s.io/pause"), Targets: []v1alpha1.TargetConfiguration{target(prefix("other.example.com/pause"), "aws", "eu-west-1", "us-east-1")}},
+					{Source: prefix("registry.k8s.io"), Targets: []v1alpha1.TargetConfiguration{
+						target(prefix("mirror.example.com/k8s"), "aws", "eu-west-1"),
+						target(prefix("mirror.example.com/k8s"), "gcp"),
+					}},
+				},
+			})).To(ConsistOf(Warning{
+				Field:  "overwrites[0].targets[0]",
+				Detail: `overlaps with overwrites[1] with prefix "registry.k8s.io" which rewrites the same images to "mirror.example.com/k8s/pause" instead of "other.example.com/pause" for provider "aws" and region "eu-west-1"`,
+			}))
+		})
+
+		It("should warn about containerd hosts which are defined more than once", func() {
+			Expect(LintConfiguration(&v1alpha1.Configuration{
+				Containerd: []v1alpha1.ContainerdConfiguration{
+					{Upstream: "docker.io", Server: "https://registry-1.docker.io", Hosts: []v1alpha1.ContainerdHostConfig{
+						{URL: "https://a.example.com", Provider: "aws", Regions: []string{"eu-west-1"}},
+						{URL: "https://b.example.com", Provider: "aws", Regions: []string{"eu-west-1", "us-east-1"}},
+						{URL: "https://c.example.com", Provider: "gcp"},
+					}},
+					{Upstream: "docker.io", Server: "https://registry-1.docker.io", Hosts: []v1alpha1.ContainerdHostConfig{
+						{URL: "https://d.example.com", Provider: "gcp", Regions: []string{"europe-west1"}},
+					}},
+					{Upstream: "ghcr.io", Server: "https://ghcr.io", Hosts: []v1alpha1.ContainerdHostConfig{
+						{URL: "https://e.example.com", Provider: "aws", Regions: []string{"eu-west-1"}},
+					}},
+				},
+			})).To(ConsistOf(
+				Warning{
+					Field:  "containerd[0].hosts[1]",
+					Detail: `upstream "docker.io" is already defined for provider "aws" and region "eu-west-1" in containerd[0].hosts[0], only one of them is used`,
+				},
+				Warning{
+					Field:  "containerd[1].hosts[0]",
+					Detail: `upstream "docker.io" is already defined for provider "gcp" and region "europe-west1" in containerd[0].hosts[2], only one of them is used`,
+				},
+			))
+		})
+	})
+})
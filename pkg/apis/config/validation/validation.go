@@ -5,23 +5,100 @@
 package validation
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/selector"
+)
+
+var (
+	supportedWorkloadKinds   = sets.New("Deployment", "DaemonSet", "StatefulSet", "Job", "CronJob")
+	supportedMatchingModes   = sets.New(v1alpha1.MatchingModeFirstMatch, v1alpha1.MatchingModeLongestPrefix)
+	supportedCapabilities    = sets.New(v1alpha1.ContainerdCapabilityPull, v1alpha1.ContainerdCapabilityResolve, v1alpha1.ContainerdCapabilityPush)
+	supportedMergeStrategies = sets.New(v1alpha1.MergeStrategySkip, v1alpha1.MergeStrategyAppend, v1alpha1.MergeStrategyPrepend, v1alpha1.MergeStrategyReplace)
+	// headerNamePattern matches HTTP header names, see https://www.rfc-editor.org/rfc/rfc9110#name-field-names.
+	headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
 )
 
 // ValidateConfiguration validates the passed configuration object.
 func ValidateConfiguration(config *v1alpha1.Configuration) field.ErrorList {
+	return validateConfiguration(config, nil)
+}
+
+func validateConfiguration(config *v1alpha1.Configuration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if config.Matching != "" && !supportedMatchingModes.Has(config.Matching) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("matching"), config.Matching, sets.List(supportedMatchingModes)))
+	}
+	allErrs = append(allErrs, ValidateOverwrites(config.Overwrites, fldPath.Child("overwrites"))...)
+	allErrs = append(allErrs, ValidateContainerd(config.Containerd, fldPath.Child("containerd"))...)
+	allErrs = append(allErrs, validatePodWebhook(config.PodWebhook, fldPath.Child("podWebhook"))...)
+
+	return allErrs
+}
+
+func validatePodWebhook(podWebhook *v1alpha1.PodWebhookConfiguration, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 
-	for i, overwrite := range config.Overwrites {
-		fldOverwrites := field.NewPath("overwrites").Index(i)
+	if podWebhook == nil {
+		return allErrs
+	}
+
+	opts := metav1validation.LabelSelectorValidationOptions{}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(podWebhook.NamespaceSelector, opts, fldPath.Child("namespaceSelector"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(podWebhook.ObjectSelector, opts, fldPath.Child("objectSelector"))...)
+
+	kinds := sets.New[string]()
+	for i, kind := range podWebhook.WorkloadKinds {
+		fldKind := fldPath.Child("workloadKinds").Index(i)
 
-		if overwrite.Source.Image == nil && overwrite.Source.Prefix == nil {
-			allErrs = append(allErrs, field.Required(fldOverwrites.Child("source"), "either 'image' or 'prefix' must be set"))
+		if !supportedWorkloadKinds.Has(kind) {
+			allErrs = append(allErrs, field.NotSupported(fldKind, kind, sets.List(supportedWorkloadKinds)))
 		}
-		if overwrite.Source.Image != nil && overwrite.Source.Prefix != nil {
-			allErrs = append(allErrs, field.Forbidden(fldOverwrites.Child("source"), "only one of 'image' or 'prefix' can be set"))
+		if kinds.Has(kind) {
+			allErrs = append(allErrs, field.Duplicate(fldKind, kind))
+		}
+		kinds.Insert(kind)
+	}
+
+	return allErrs
+}
+
+// ValidateOverwrites validates the passed image overwrites.
+func ValidateOverwrites(overwrites []v1alpha1.ImageOverwrite, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, overwrite := range overwrites {
+		fldOverwrites := fldPath.Index(i)
+
+		var sourcePattern *regexp.Regexp
+
+		switch sources := countSet(overwrite.Source.Image, overwrite.Source.Prefix, overwrite.Source.Regex); {
+		case sources == 0:
+			allErrs = append(allErrs, field.Required(fldOverwrites.Child("source"), "either 'image', 'prefix' or 'regex' must be set"))
+		case sources > 1:
+			allErrs = append(allErrs, field.Forbidden(fldOverwrites.Child("source"), "only one of 'image', 'prefix' or 'regex' can be set"))
+		case overwrite.Source.Regex != nil:
+			var err error
+			if sourcePattern, err = image.CompileSourcePattern(*overwrite.Source.Regex); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldOverwrites.Child("source", "regex"), *overwrite.Source.Regex, err.Error()))
+			}
+		case overwrite.Source.Image != nil:
+			if _, err := image.ParseNormalizedReference(*overwrite.Source.Image); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldOverwrites.Child("source", "image"), *overwrite.Source.Image, err.Error()))
+			}
 		}
 		if len(overwrite.Targets) == 0 {
 			allErrs = append(allErrs, field.Required(fldOverwrites.Child("targets"), "at least one target must be specified"))
@@ -30,6 +107,8 @@ func ValidateConfiguration(config *v1alpha1.Configuration) field.ErrorList {
 			fldTarget := fldOverwrites.Child("targets").Index(j)
 
 			switch {
+			case target.Regex != nil:
+				allErrs = append(allErrs, field.Forbidden(fldTarget.Child("regex"), "'regex' is only supported for sources"))
 			case target.Image.Image == nil && target.Prefix == nil:
 				allErrs = append(allErrs, field.Required(fldTarget.Child("image"), "either 'image' or 'prefix' must be set"))
 			case target.Prefix != nil && target.Image.Image != nil:
@@ -41,6 +120,18 @@ func ValidateConfiguration(config *v1alpha1.Configuration) field.ErrorList {
 				if target.Image.Image != nil {
 					allErrs = append(allErrs, field.Forbidden(fldTarget.Child("image"), "target 'image' must not be set when source 'prefix' is set"))
 				}
+			case overwrite.Source.Regex != nil:
+				if target.Image.Image == nil {
+					allErrs = append(allErrs, field.Required(fldTarget.Child("image"), "target 'image' must be set when source 'regex' is set"))
+				}
+				if target.Prefix != nil {
+					allErrs = append(allErrs, field.Forbidden(fldTarget.Child("prefix"), "target must not set 'prefix' when source 'regex' is set"))
+				}
+				if sourcePattern != nil && target.Image.Image != nil {
+					for _, name := range image.UnknownGroupReferences(sourcePattern, *target.Image.Image) {
+						allErrs = append(allErrs, field.Invalid(fldTarget.Child("image"), *target.Image.Image, fmt.Sprintf("capture group %q does not exist in source 'regex'", name)))
+					}
+				}
 			case overwrite.Source.Image != nil:
 				if target.Image.Image == nil {
 					allErrs = append(allErrs, field.Required(fldTarget.Child("image"), "target 'image' must be set when source 'image' is set"))
@@ -52,13 +143,168 @@ func ValidateConfiguration(config *v1alpha1.Configuration) field.ErrorList {
 
 			if target.Provider == "" {
 				allErrs = append(allErrs, field.Required(fldTarget.Child("provider"), "provider must be specified"))
+			} else if err := selector.ValidatePattern(target.Provider); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldTarget.Child("provider"), target.Provider, fmt.Sprintf("invalid provider pattern: %v", err)))
+			}
+
+			allErrs = append(allErrs, validateRegions(target.Regions, fldTarget.Child("regions"))...)
+		}
+	}
+
+	return allErrs
+}
+
+// ValidateContainerd validates the passed containerd upstream configurations.
+func ValidateContainerd(containerdConfigs []v1alpha1.ContainerdConfiguration, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, containerdConfig := range containerdConfigs {
+		fldContainerd := fldPath.Index(i)
+
+		if containerdConfig.Upstream == "" {
+			allErrs = append(allErrs, field.Required(fldContainerd.Child("upstream"), "upstream must be specified"))
+		}
+		switch {
+		case containerdConfig.Upstream == v1alpha1.DefaultUpstream && containerdConfig.Server != "":
+			allErrs = append(allErrs, field.Forbidden(fldContainerd.Child("server"), fmt.Sprintf("server must not be specified for upstream %q", v1alpha1.DefaultUpstream)))
+		case containerdConfig.Upstream != v1alpha1.DefaultUpstream && containerdConfig.Server == "":
+			allErrs = append(allErrs, field.Required(fldContainerd.Child("server"), "server must be specified"))
+		case containerdConfig.Server != "":
+			allErrs = append(allErrs, validateURL(containerdConfig.Server, fldContainerd.Child("server"))...)
+		}
+		if len(containerdConfig.Hosts) == 0 {
+			allErrs = append(allErrs, field.Required(fldContainerd.Child("hosts"), "at least one host must be specified"))
+		}
+		if containerdConfig.MergeStrategy != "" && !supportedMergeStrategies.Has(containerdConfig.MergeStrategy) {
+			allErrs = append(allErrs, field.NotSupported(fldContainerd.Child("mergeStrategy"), containerdConfig.MergeStrategy, sets.List(supportedMergeStrategies)))
+		}
+		for j, host := range containerdConfig.Hosts {
+			fldHost := fldContainerd.Child("hosts").Index(j)
+
+			switch {
+			case host.URL == "" && len(host.URLs) == 0:
+				allErrs = append(allErrs, field.Required(fldHost.Child("url"), "either 'url' or 'urls' must be specified"))
+			case host.URL != "" && len(host.URLs) > 0:
+				allErrs = append(allErrs, field.Forbidden(fldHost.Child("urls"), "only one of 'url' or 'urls' can be set"))
+			case host.URL != "":
+				allErrs = append(allErrs, validateURL(host.URL, fldHost.Child("url"))...)
+			}
+			for k, hostURL := range host.URLs {
+				if hostURL == "" {
+					allErrs = append(allErrs, field.Invalid(fldHost.Child("urls").Index(k), hostURL, "url must not be empty"))
+				} else {
+					allErrs = append(allErrs, validateURL(hostURL, fldHost.Child("urls").Index(k))...)
+				}
 			}
+			if host.Provider == "" {
+				allErrs = append(allErrs, field.Required(fldHost.Child("provider"), "provider must be specified"))
+			} else if err := selector.ValidatePattern(host.Provider); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldHost.Child("provider"), host.Provider, fmt.Sprintf("invalid provider pattern: %v", err)))
+			}
+
+			allErrs = append(allErrs, validateRegions(host.Regions, fldHost.Child("regions"))...)
 
-			for k, region := range target.Regions {
-				if region == "" {
-					allErrs = append(allErrs, field.Invalid(fldTarget.Child("regions").Index(k), region, "region must not be empty"))
+			if host.CA != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(host.CA)) {
+				allErrs = append(allErrs, field.Invalid(fldHost.Child("ca"), host.CA, "ca must contain PEM encoded certificates"))
+			}
+			if host.Client != nil {
+				if _, err := tls.X509KeyPair([]byte(host.Client.Cert), []byte(host.Client.Key)); err != nil {
+					// The value is omitted to not reveal the private key.
+					allErrs = append(allErrs, field.Invalid(fldHost.Child("client"), "", fmt.Sprintf("client certificate and key are invalid: %v", err)))
 				}
 			}
+
+			allErrs = append(allErrs, validateCapabilities(host.Capabilities, fldHost.Child("capabilities"))...)
+			allErrs = append(allErrs, validateHeader(host.Header, fldHost.Child("header"))...)
+		}
+	}
+
+	return allErrs
+}
+
+func countSet(values ...*string) int {
+	count := 0
+	for _, value := range values {
+		if value != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// validateURL validates that the given value is an absolute http or https URL. The URLs are rendered into the hosts.toml
+// files of containerd, hence quotes, brackets besides IPv6 addresses, whitespace and control characters are rejected.
+func validateURL(value string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if strings.ContainsFunc(value, func(r rune) bool { return r == '"' || unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return append(allErrs, field.Invalid(fldPath, value, "url must not contain quotes, whitespace or control characters"))
+	}
+
+	u, err := url.Parse(value)
+	switch {
+	case err != nil:
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("invalid url: %v", err)))
+	case u.Scheme != "http" && u.Scheme != "https":
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "url must use the http or https scheme"))
+	case u.Hostname() == "":
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "url must contain a host"))
+	case u.User != nil || u.RawQuery != "" || u.Fragment != "":
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "url must not contain user information, a query or a fragment"))
+	case strings.ContainsAny(u.Hostname(), "[]") || strings.ContainsAny(u.EscapedPath(), "[]"):
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "url must not contain brackets besides an IPv6 address"))
+	}
+
+	return allErrs
+}
+
+func validateCapabilities(capabilities []v1alpha1.ContainerdCapability, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	seen := sets.New[v1alpha1.ContainerdCapability]()
+	for i, capability := range capabilities {
+		switch {
+		case !supportedCapabilities.Has(capability):
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i), capability, sets.List(supportedCapabilities)))
+		case seen.Has(capability):
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), capability))
+		}
+		seen.Insert(capability)
+	}
+
+	return allErrs
+}
+
+func validateHeader(header map[string][]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for name, values := range header {
+		fldHeader := fldPath.Key(name)
+
+		if !headerNamePattern.MatchString(name) {
+			allErrs = append(allErrs, field.Invalid(fldHeader, name, "header name must be a valid HTTP field name"))
+		}
+		if len(values) == 0 {
+			allErrs = append(allErrs, field.Required(fldHeader, "at least one value must be specified"))
+		}
+		for i, value := range values {
+			if strings.ContainsFunc(value, unicode.IsControl) {
+				allErrs = append(allErrs, field.Invalid(fldHeader.Index(i), value, "header value must not contain control characters"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateRegions(regions []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, region := range regions {
+		if region == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), region, "region must not be empty"))
+		} else if err := selector.ValidatePattern(region); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), region, fmt.Sprintf("invalid region pattern: %v", err)))
 		}
 	}
 
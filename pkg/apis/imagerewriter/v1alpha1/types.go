@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageRewriterConfig contains the shoot specific image rewriter configuration.
+// It is passed via the provider config of the Extension resource and merged on top of the global configuration.
+type ImageRewriterConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Containerd contains additional containerd upstream configurations for the shoot.
+	// An upstream configured here replaces the upstream with the same name of the global configuration.
+	// +optional
+	Containerd []configv1alpha1.ContainerdConfiguration `json:"containerd,omitempty"`
+	// Overwrites configure additional source and target images that should be replaced.
+	// They are matched before the overwrites of the global configuration in the 'FirstMatch' mode. In the 'LongestPrefix'
+	// mode, they only take precedence over global overwrites of the same precedence.
+	// +optional
+	Overwrites []configv1alpha1.ImageOverwrite `json:"overwrites,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageRewriterStatus contains the effective image rewriter configuration for the provider and region of the shoot.
+// It is published in the provider status of the Extension resource.
+type ImageRewriterStatus struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ShootWebhooksInstalled indicates whether the webhooks which rewrite the images of pods are installed in the shoot.
+	ShootWebhooksInstalled bool `json:"shootWebhooksInstalled"`
+	// ConfigFingerprint is the fingerprint of the effective configuration. The Extension is reconciled again if the
+	// fingerprint of the current configuration differs.
+	// +optional
+	ConfigFingerprint string `json:"configFingerprint,omitempty"`
+	// Overwrites are the overwrites which apply to the shoot in the order they are matched.
+	// +optional
+	Overwrites []EffectiveOverwrite `json:"overwrites,omitempty"`
+	// Containerd are the containerd upstreams which are configured for the shoot.
+	// +optional
+	Containerd []EffectiveContainerdUpstream `json:"containerd,omitempty"`
+}
+
+// EffectiveOverwrite contains an overwrite with the target which applies to the shoot.
+type EffectiveOverwrite struct {
+	// Source is the source of the overwrite.
+	Source configv1alpha1.Image `json:"source"`
+	// Target is the target for the shoot's provider and region.
+	Target configv1alpha1.Image `json:"target"`
+}
+
+// EffectiveContainerdUpstream contains a containerd upstream with the hosts which apply to the shoot.
+type EffectiveContainerdUpstream struct {
+	// Upstream is the upstream name of the registry.
+	Upstream string `json:"upstream"`
+	// Server is the URL of the upstream registry.
+	Server string `json:"server"`
+	// Hosts are the URLs of the hosts for the shoot's provider and region. They are tried in the given order.
+	Hosts []string `json:"hosts"`
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/event"
+)
+
+// ConfigMapControllerName is the name of the controller which reloads the configuration from a ConfigMap.
+const ConfigMapControllerName = "config-configmap"
+
+// ConfigMapReconciler reloads the configuration from a key of a ConfigMap whenever the ConfigMap changes.
+type ConfigMapReconciler struct {
+	Client   client.Reader
+	Reloader *Reloader
+	// ConfigMap is the namespace and name of the ConfigMap.
+	ConfigMap types.NamespacedName
+	// Key is the key of the ConfigMap data which contains the configuration.
+	Key string
+	// Recorder records a warning event on the ConfigMap if its configuration cannot be reloaded.
+	Recorder events.EventRecorder
+}
+
+// AddToManager adds the ConfigMapReconciler to the given manager. The ConfigMap is read from a dedicated cache which
+// only contains this ConfigMap, so that the cache of the manager is not restricted.
+func (r *ConfigMapReconciler) AddToManager(mgr manager.Manager) error {
+	configMapCluster, err := cluster.New(mgr.GetConfig(), func(opts *cluster.Options) {
+		opts.Scheme = mgr.GetScheme()
+		opts.Logger = mgr.GetLogger().WithName(ConfigMapControllerName)
+		opts.HTTPClient = mgr.GetHTTPClient()
+		opts.Cache.DefaultNamespaces = map[string]cache.Config{r.ConfigMap.Namespace: {}}
+		opts.Cache.ByObject = map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Field: fields.OneTermEqualSelector(metav1.ObjectNameField, r.ConfigMap.Name)},
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("could not create cache for configmap %s: %w", r.ConfigMap, err)
+	}
+	if err := mgr.Add(configMapCluster); err != nil {
+		return fmt.Errorf("could not add cache for configmap %s to manager: %w", r.ConfigMap, err)
+	}
+
+	if r.Client == nil {
+		r.Client = configMapCluster.GetCache()
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorder(ConfigMapControllerName)
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named(ConfigMapControllerName).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: 1,
+			// The configuration is reloaded by all replicas because all of them serve the webhooks.
+			NeedLeaderElection: ptr.To(false),
+		}).
+		WatchesRawSource(source.Kind(configMapCluster.GetCache(), &corev1.ConfigMap{}, &handler.TypedEnqueueRequestForObject[*corev1.ConfigMap]{})).
+		Complete(r)
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+	if err := r.Reloader.Reload(readConfigMap(ctx, r.Client, r.ConfigMap, r.Key)); err != nil && r.Recorder != nil {
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: r.ConfigMap.Namespace, Name: r.ConfigMap.Name}}
+		r.Recorder.Eventf(configMap, nil, corev1.EventTypeWarning, event.ReasonConfigurationReloadFailed, event.ActionReload, "%s", err.Error())
+	}
+	return reconcile.Result{}, nil
+}
+
+// readConfigMap returns the configuration in the given key of the ConfigMap.
+func readConfigMap(ctx context.Context, reader client.Reader, key types.NamespacedName, dataKey string) ([]Source, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, key, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s: %w", key, err)
+	}
+
+	data, ok := configMap.Data[dataKey]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s has no key %q", key, dataKey)
+	}
+
+	return []Source{{Name: key.String() + "/" + dataKey, Data: []byte(data)}}, nil
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/cmd"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+var _ = Describe("ConfigMap", func() {
+	var (
+		ctx        = context.Background()
+		fakeClient client.Client
+		configMap  *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		fakeClient = fakeclient.NewClientBuilder().Build()
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "image-rewriter-config", Namespace: "garden"},
+			Data:       map[string]string{"config.yaml": validConfig},
+		}
+	})
+
+	Describe("ConfigMapReconciler", func() {
+		var (
+			store      *configutils.Store
+			reloader   *Reloader
+			recorder   *events.FakeRecorder
+			reconciler *ConfigMapReconciler
+		)
+
+		BeforeEach(func() {
+			store = configutils.NewStore(&v1alpha1.Configuration{})
+			reloader = NewReloader(logr.Discard(), store)
+			recorder = events.NewFakeRecorder(1)
+			reconciler = &ConfigMapReconciler{
+				Client:    fakeClient,
+				Reloader:  reloader,
+				ConfigMap: client.ObjectKeyFromObject(configMap),
+				Key:       "config.yaml",
+				Recorder:  recorder,
+			}
+		})
+
+		It("should reload the configuration from the ConfigMap", func() {
+			Expect(fakeClient.Create(ctx, configMap)).To(Succeed())
+
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})).To(Equal(reconcile.Result{}))
+			Expect(store.Get().Overwrites).To(HaveLen(1))
+			Expect(reloader.Err()).To(Succeed())
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should keep the configuration if the ConfigMap is invalid", func() {
+			configMap.Data["config.yaml"] = invalidConfig
+			Expect(fakeClient.Create(ctx, configMap)).To(Succeed())
+
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})).To(Equal(reconcile.Result{}))
+			Expect(store.Get().Overwrites).To(BeEmpty())
+			Expect(reloader.Err()).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning ConfigurationReloadFailed failed to reload configuration")))
+
+			// The unchanged ConfigMap is not reported again.
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})).To(Equal(reconcile.Result{}))
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should keep the configuration if the key does not exist", func() {
+			reconciler.Key = "other.yaml"
+			Expect(fakeClient.Create(ctx, configMap)).To(Succeed())
+
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})).To(Equal(reconcile.Result{}))
+			Expect(store.Get().Overwrites).To(BeEmpty())
+			Expect(reloader.Err()).To(MatchError(ContainSubstring(`has no key "other.yaml"`)))
+		})
+
+		It("should keep the configuration if the ConfigMap was deleted", func() {
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})).To(Equal(reconcile.Result{}))
+			Expect(store.Get().Overwrites).To(BeEmpty())
+			Expect(reloader.Err()).To(HaveOccurred())
+		})
+	})
+
+	Describe("ExtensionOptions", func() {
+		It("should load the configuration from the ConfigMap", func() {
+			Expect(fakeClient.Create(ctx, configMap)).To(Succeed())
+
+			options := &ExtensionOptions{ConfigMapName: configMap.Name, ConfigMapNamespace: configMap.Namespace, ConfigMapKey: "config.yaml"}
+			Expect(options.Complete()).To(Succeed())
+			Expect(options.Completed().Load(ctx, fakeClient)).To(Succeed())
+
+			var store *configutils.Store
+			options.Completed().Apply(&store)
+			Expect(store.Get().Overwrites).To(ConsistOf(v1alpha1.ImageOverwrite{
+				Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com")},
+				Targets: []v1alpha1.TargetConfiguration{
+					{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com")}, Provider: "local"},
+				},
+			}))
+		})
+
+		It("should fail to load an invalid configuration from the ConfigMap", func() {
+			configMap.Data["config.yaml"] = invalidConfig
+			Expect(fakeClient.Create(ctx, configMap)).To(Succeed())
+
+			options := &ExtensionOptions{ConfigMapName: configMap.Name, ConfigMapNamespace: configMap.Namespace, ConfigMapKey: "config.yaml"}
+			Expect(options.Complete()).To(Succeed())
+			Expect(options.Completed().Load(ctx, fakeClient)).To(HaveOccurred())
+		})
+
+		It("should not allow both a file and a ConfigMap", func() {
+			options := &ExtensionOptions{ConfigLocations: []string{"/etc/config.yaml"}, ConfigMapName: configMap.Name, ConfigMapNamespace: configMap.Namespace, ConfigMapKey: "config.yaml"}
+			Expect(options.Complete()).To(MatchError(ContainSubstring("mutually exclusive")))
+		})
+
+		It("should require a namespace for the ConfigMap", func() {
+			options := &ExtensionOptions{ConfigMapName: configMap.Name, ConfigMapKey: "config.yaml"}
+			Expect(options.Complete()).To(MatchError(ContainSubstring("namespace is not set")))
+		})
+
+		It("should require a configuration source", func() {
+			Expect((&ExtensionOptions{}).Complete()).To(MatchError(ContainSubstring("config location is not set")))
+		})
+	})
+})
@@ -5,20 +5,25 @@
 package cmd
 
 import (
+	"context"
 	"errors"
-	"os"
+	"fmt"
 
 	"github.com/gardener/gardener/extensions/pkg/controller/cmd"
 	extensionsheartbeatcontroller "github.com/gardener/gardener/extensions/pkg/controller/heartbeat"
 	webhookcmd "github.com/gardener/gardener/extensions/pkg/webhook/cmd"
+	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/controller"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	containerdwebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/containerd"
 	imagewebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/image"
 	podwebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/pod"
@@ -38,37 +43,56 @@ func init() {
 
 // ExtensionOptions holds options related to the image rewriter.
 type ExtensionOptions struct {
-	ConfigLocation string
-	config         *ExtensionConfig
+	ConfigLocations    []string
+	ConfigMapName      string
+	ConfigMapNamespace string
+	ConfigMapKey       string
+	config             *ExtensionConfig
 }
 
 // AddFlags implements Flagger.AddFlags.
 func (o *ExtensionOptions) AddFlags(fs *pflag.FlagSet) {
-	fs.StringVar(&o.ConfigLocation, "config", "", "Path to image rewriter configuration")
+	fs.StringArrayVar(&o.ConfigLocations, "config", nil, "Path to an image rewriter configuration file or to a directory containing configuration files, can be given multiple times")
+	fs.StringVar(&o.ConfigMapName, "config-configmap-name", "", "Name of the ConfigMap containing the image rewriter configuration, mutually exclusive with --config")
+	fs.StringVar(&o.ConfigMapNamespace, "config-configmap-namespace", o.ConfigMapNamespace, "Namespace of the ConfigMap containing the image rewriter configuration")
+	fs.StringVar(&o.ConfigMapKey, "config-configmap-key", "config.yaml", "Key of the ConfigMap data containing the image rewriter configuration")
 }
 
 // Complete implements Completer.Complete.
 func (o *ExtensionOptions) Complete() error {
-	if o.ConfigLocation == "" {
+	switch {
+	case len(o.ConfigLocations) > 0 && o.ConfigMapName != "":
+		return errors.New("config location and config configmap name are mutually exclusive")
+
+	case o.ConfigMapName != "":
+		if o.ConfigMapNamespace == "" {
+			return errors.New("config configmap namespace is not set")
+		}
+		if o.ConfigMapKey == "" {
+			return errors.New("config configmap key is not set")
+		}
+
+		// The configuration is loaded when a client is available, see ExtensionConfig.Load.
+		o.config = &ExtensionConfig{
+			configMap:    types.NamespacedName{Namespace: o.ConfigMapNamespace, Name: o.ConfigMapName},
+			configMapKey: o.ConfigMapKey,
+			store:        configutils.NewStore(nil),
+		}
+
+	case len(o.ConfigLocations) > 0:
+		config, err := decodeSources(ReadFiles(o.ConfigLocations))
+		if err != nil {
+			return err
+		}
+
+		o.config = &ExtensionConfig{
+			locations: o.ConfigLocations,
+			store:     configutils.NewStore(config),
+		}
+
+	default:
 		return errors.New("config location is not set")
 	}
-	data, err := os.ReadFile(o.ConfigLocation)
-	if err != nil {
-		return err
-	}
-
-	config := v1alpha1.Configuration{}
-	if err := runtime.DecodeInto(decoder, data, &config); err != nil {
-		return err
-	}
-
-	if errs := validation.ValidateConfiguration(&config); len(errs) > 0 {
-		return errs.ToAggregate()
-	}
-
-	o.config = &ExtensionConfig{
-		config: config,
-	}
 
 	return nil
 }
@@ -80,12 +104,56 @@ func (o *ExtensionOptions) Completed() *ExtensionConfig {
 
 // ExtensionConfig contains configuration information about the image rewriter.
 type ExtensionConfig struct {
-	config v1alpha1.Configuration
+	locations    []string
+	configMap    types.NamespacedName
+	configMapKey string
+	store        *configutils.Store
+}
+
+// Apply applies the ExtensionOptions to the passed ControllerOptions instance. All instances share the same store, hence
+// they observe reloads of the configuration.
+func (c *ExtensionConfig) Apply(store **configutils.Store) {
+	*store = c.store
+}
+
+// Load loads the configuration from the ConfigMap with the given reader. It must be called before the configuration is
+// applied if the configuration is read from a ConfigMap and is a no-op otherwise.
+func (c *ExtensionConfig) Load(ctx context.Context, reader client.Reader) error {
+	if c.configMap.Name == "" {
+		return nil
+	}
+
+	config, err := decodeSources(readConfigMap(ctx, reader, c.configMap, c.configMapKey))
+	if err != nil {
+		return err
+	}
+
+	c.store.Set(config)
+	return nil
 }
 
-// Apply applies the ExtensionOptions to the passed ControllerOptions instance.
-func (c *ExtensionConfig) Apply(config *v1alpha1.Configuration) {
-	*config = c.config
+// AddReloaderToManager adds the components which reload the configuration when its source changes to the manager,
+// together with a readiness check which fails if the last reload failed.
+func (c *ExtensionConfig) AddReloaderToManager(log logr.Logger, mgr manager.Manager) error {
+	reloader := NewReloader(log, c.store)
+
+	if c.configMap.Name != "" {
+		if err := (&ConfigMapReconciler{
+			Reloader:  reloader,
+			ConfigMap: c.configMap,
+			Key:       c.configMapKey,
+		}).AddToManager(mgr); err != nil {
+			return fmt.Errorf("could not add configmap reloader to manager: %w", err)
+		}
+	} else if err := mgr.Add(NewFileWatcher(reloader, c.locations)); err != nil {
+		return fmt.Errorf("could not add configuration file watcher to manager: %w", err)
+	}
+
+	if err := mgr.AddReadyzCheck("config", reloader.Checker); err != nil {
+		return fmt.Errorf("could not add ready check for configuration to manager: %w", err)
+	}
+
+	return nil
 }
 
 // ControllerSwitches are the cmd.SwitchOptions for the provider controllers.
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+// Reloader replaces the configuration of the store when the content of the configuration source changes.
+// If the changed content is invalid, the last valid configuration is kept. Failed reloads are logged and counted, see
+// metrics.RecordConfigReload, and the 'config' check fails until a valid configuration is loaded, see Checker.
+type Reloader struct {
+	log   logr.Logger
+	store *configutils.Store
+
+	lock sync.RWMutex
+	// sources are the configuration sources which were read last.
+	sources []Source
+	// err is the error of the last reload.
+	err error
+}
+
+// NewReloader creates a new Reloader for the given store.
+func NewReloader(log logr.Logger, store *configutils.Store) *Reloader {
+	return &Reloader{
+		log:   log.WithName("config-reloader"),
+		store: store,
+	}
+}
+
+// Reload decodes, validates and merges the given configuration sources and replaces the configuration of the store if
+// the sources changed and are valid. readErr is the error which occurred while reading the sources, if any.
+// It returns the error of the reload, unchanged sources are not reloaded and return no error.
+func (r *Reloader) Reload(sources []Source, readErr error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err := readErr
+	if err == nil && r.sources != nil && equalSources(sources, r.sources) {
+		return nil
+	}
+
+	if err == nil {
+		r.sources = sources
+		config, decodeErr := decodeSources(sources, nil)
+		if decodeErr == nil {
+			r.store.Set(config)
+		}
+		err = decodeErr
+	}
+
+	metrics.RecordConfigReload(err)
+	if err != nil {
+		r.err = fmt.Errorf("failed to reload configuration, keeping the previous configuration: %w", err)
+		r.log.Error(err, "Failed to reload configuration, keeping the previous configuration")
+		return r.err
+	}
+
+	r.err = nil
+	r.log.Info("Reloaded configuration")
+
+	for _, warning := range validation.LintConfiguration(r.store.Get()) {
+		r.log.Info("Configuration warning", "field", warning.Field, "detail", warning.Detail)
+	}
+	return nil
+}
+
+// Err returns the error of the last reload of the configuration, if any.
+func (r *Reloader) Err() error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.err
+}
+
+// Checker is a health check which fails if the last reload of the configuration failed. The webhooks keep serving with
+// the last valid configuration, hence the readiness probe of the Helm chart excludes the check.
+func (r *Reloader) Checker(_ *http.Request) error {
+	return r.Err()
+}
+
+// FileWatcher reloads the configuration files whenever they change.
+type FileWatcher struct {
+	reloader  *Reloader
+	locations []string
+}
+
+var (
+	_ manager.Runnable               = (*FileWatcher)(nil)
+	_ manager.LeaderElectionRunnable = (*FileWatcher)(nil)
+)
+
+// NewFileWatcher creates a new FileWatcher which reloads the configuration files at the given locations with the given
+// Reloader. A location is either a file or a directory containing configuration files, see ReadFiles.
+func NewFileWatcher(reloader *Reloader, locations []string) *FileWatcher {
+	return &FileWatcher{
+		reloader:  reloader,
+		locations: locations,
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. The configuration is reloaded by all replicas because
+// all of them serve the webhooks.
+func (w *FileWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable. It reloads the configuration files whenever they change until the context is done.
+func (w *FileWatcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher for configuration file: %w", err)
+	}
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			w.reloader.log.Error(err, "Failed to close watcher for configuration file")
+		}
+	}()
+
+	// Watch the directories instead of the files, ConfigMap volumes replace the files by swapping a symbolic link.
+	directories := sets.New[string]()
+	for _, location := range w.locations {
+		if info, err := os.Stat(location); err == nil && info.IsDir() {
+			directories.Insert(location)
+		} else {
+			directories.Insert(filepath.Dir(location))
+		}
+	}
+	for _, directory := range sets.List(directories) {
+		if err := watcher.Add(directory); err != nil {
+			return fmt.Errorf("failed to watch configuration directory %s: %w", directory, err)
+		}
+	}
+
+	// The file might have changed since the configuration was loaded initially.
+	w.Reload()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.Reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.reloader.log.Error(err, "Error watching configuration files")
+		}
+	}
+}
+
+// Reload reads the configuration files and reloads them.
+func (w *FileWatcher) Reload() {
+	// Failed reloads are logged and counted by the reloader.
+	_ = w.reloader.Reload(ReadFiles(w.locations))
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/cmd"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+const (
+	validConfig = `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    prefix: registry.example.com
+  targets:
+  - prefix: mirror.example.com
+    provider: local
+`
+	invalidConfig = `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    prefix: registry.example.com
+`
+)
+
+var _ = Describe("Reloader", func() {
+	var (
+		location string
+		initial  *v1alpha1.Configuration
+		store    *configutils.Store
+		reloader *Reloader
+		watcher  *FileWatcher
+	)
+
+	BeforeEach(func() {
+		location = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(location, []byte(`apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+`), 0600)).To(Succeed())
+
+		initial = &v1alpha1.Configuration{}
+		store = configutils.NewStore(initial)
+		reloader = NewReloader(logr.Discard(), store)
+		watcher = NewFileWatcher(reloader, []string{location})
+
+		metrics.ConfigReloads.Reset()
+	})
+
+	expectedConfig := &v1alpha1.Configuration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "config.image-rewriter.extensions.gardener.cloud/v1alpha1",
+			Kind:       "Configuration",
+		},
+		Overwrites: []v1alpha1.ImageOverwrite{
+			{
+				Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com")},
+				Targets: []v1alpha1.TargetConfiguration{
+					{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com")}, Provider: "local"},
+				},
+			},
+		},
+	}
+
+	Describe("#Reload", func() {
+		It("should replace the configuration if the file is valid", func() {
+			Expect(os.WriteFile(location, []byte(validConfig), 0600)).To(Succeed())
+
+			watcher.Reload()
+			Expect(store.Get()).To(Equal(expectedConfig))
+			Expect(reloader.Err()).To(Succeed())
+			Expect(testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("success"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(metrics.ConfigLastReloadSuccessful)).To(Equal(1.0))
+		})
+
+		It("should keep the last valid configuration if the file is invalid", func() {
+			Expect(os.WriteFile(location, []byte(validConfig), 0600)).To(Succeed())
+			watcher.Reload()
+			valid := store.Get()
+
+			Expect(os.WriteFile(location, []byte(invalidConfig), 0600)).To(Succeed())
+			watcher.Reload()
+			Expect(store.Get()).To(BeIdenticalTo(valid))
+			Expect(reloader.Err()).To(MatchError(ContainSubstring("keeping the previous configuration")))
+			Expect(reloader.Checker(nil)).To(HaveOccurred())
+			Expect(testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("failure"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(metrics.ConfigLastReloadSuccessful)).To(Equal(0.0))
+
+			Expect(os.WriteFile(location, []byte(validConfig+"\n"), 0600)).To(Succeed())
+			watcher.Reload()
+			Expect(store.Get()).To(Equal(expectedConfig))
+			Expect(reloader.Err()).To(Succeed())
+			Expect(reloader.Checker(nil)).To(Succeed())
+		})
+
+		It("should keep the configuration if the file cannot be read", func() {
+			Expect(os.Remove(location)).To(Succeed())
+
+			watcher.Reload()
+			Expect(store.Get()).To(BeIdenticalTo(initial))
+			Expect(reloader.Err()).To(HaveOccurred())
+		})
+
+		It("should not reload the configuration if the file did not change", func() {
+			watcher.Reload()
+			watcher.Reload()
+
+			Expect(testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("success"))).To(Equal(1.0))
+		})
+	})
+
+	Describe("FileWatcher", func() {
+		It("should reload the configuration when the file changes", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+
+			done := make(chan error)
+			go func() {
+				defer GinkgoRecover()
+				done <- watcher.Start(ctx)
+			}()
+
+			Eventually(func() float64 {
+				return testutil.ToFloat64(metrics.ConfigReloads.WithLabelValues("success"))
+			}).Should(Equal(1.0))
+
+			Expect(os.WriteFile(location, []byte(validConfig), 0600)).To(Succeed())
+			Eventually(store.Get).Should(Equal(expectedConfig))
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+})
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+// configFileExtensions are the extensions of the files which are read from configuration directories.
+var configFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// Source is the content of a configuration fragment together with the name of its source, e.g. the file it was read
+// from.
+type Source struct {
+	// Name is the name of the source.
+	Name string
+	// Data is the content of the source.
+	Data []byte
+}
+
+// ReadFiles reads the configuration files at the given locations in order. A location is either a file or a directory,
+// the files of a directory with the extension '.yaml', '.yml' or '.json' are read in lexical order. Hidden files are
+// ignored, e.g. the internal files of ConfigMap volumes.
+func ReadFiles(locations []string) ([]Source, error) {
+	var sources []Source
+
+	for _, location := range locations {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			data, err := os.ReadFile(location)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, Source{Name: location, Data: data})
+			continue
+		}
+
+		entries, err := os.ReadDir(location)
+		if err != nil {
+			return nil, err
+		}
+
+		var found bool
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") || !slices.Contains(configFileExtensions, filepath.Ext(entry.Name())) {
+				continue
+			}
+
+			path := filepath.Join(location, entry.Name())
+			// Follow symbolic links, the files of ConfigMap volumes are links into a hidden directory.
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, Source{Name: path, Data: data})
+			found = true
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no configuration files found in directory %s", location)
+		}
+	}
+
+	return sources, nil
+}
+
+// LoadFiles reads, validates and merges the configuration files at the given locations, see ReadFiles.
+func LoadFiles(locations []string) (*v1alpha1.Configuration, error) {
+	return decodeSources(ReadFiles(locations))
+}
+
+// LintFiles reads and validates the configuration files at the given locations like LoadFiles. It returns the
+// validation errors and, if there are none, the warnings of validation.LintConfiguration for the merged configuration.
+// An error is returned if the files cannot be read or decoded.
+func LintFiles(locations []string) (field.ErrorList, []validation.Warning, error) {
+	sources, err := ReadFiles(locations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fragments, err := decodeFragments(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if errs := validateFragments(fragments); len(errs) > 0 {
+		return errs, nil, nil
+	}
+
+	return nil, validation.LintConfiguration(mergeFragments(fragments)), nil
+}
+
+// decodeSources decodes and validates the given configuration sources and merges them in order, see
+// configutils.MergeFragments. readErr is the error which occurred while reading the sources, if any.
+func decodeSources(sources []Source, readErr error) (*v1alpha1.Configuration, error) {
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	fragments, err := decodeFragments(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := validateFragments(fragments); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	return mergeFragments(fragments), nil
+}
+
+func decodeFragments(sources []Source) ([]validation.Fragment, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no configuration found")
+	}
+
+	fragments := make([]validation.Fragment, 0, len(sources))
+	for _, source := range sources {
+		config := &v1alpha1.Configuration{}
+		if err := runtime.DecodeInto(decoder, source.Data, config); err != nil {
+			return nil, fmt.Errorf("failed to decode configuration %s: %w", source.Name, err)
+		}
+		fragments = append(fragments, validation.Fragment{Source: source.Name, Configuration: config})
+	}
+
+	return fragments, nil
+}
+
+func validateFragments(fragments []validation.Fragment) field.ErrorList {
+	// A single configuration is validated as before, i.e. without the name of its source in the field paths.
+	if len(fragments) == 1 {
+		return validation.ValidateConfiguration(fragments[0].Configuration)
+	}
+	return validation.ValidateFragments(fragments)
+}
+
+func mergeFragments(fragments []validation.Fragment) *v1alpha1.Configuration {
+	if len(fragments) == 1 {
+		return fragments[0].Configuration
+	}
+
+	configs := make([]*v1alpha1.Configuration, 0, len(fragments))
+	for _, fragment := range fragments {
+		configs = append(configs, fragment.Configuration)
+	}
+	return configutils.MergeFragments(configs...)
+}
+
+func equalSources(a, b []Source) bool {
+	return slices.EqualFunc(a, b, func(x, y Source) bool {
+		return x.Name == y.Name && bytes.Equal(x.Data, y.Data)
+	})
+}
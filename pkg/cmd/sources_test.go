@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/cmd"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+const otherConfig = `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    prefix: other.example.com
+  targets:
+  - prefix: mirror.example.com
+    provider: local
+`
+
+var _ = Describe("Sources", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	Describe("#ReadFiles", func() {
+		It("should read files in the given order and directories in lexical order", func() {
+			single := write("single.yaml", "single")
+			write("conf.d/20-b.yml", "b")
+			write("conf.d/10-a.yaml", "a")
+			write("conf.d/30-c.json", "c")
+			write("conf.d/README.md", "ignored")
+			write("conf.d/.hidden.yaml", "ignored")
+
+			sources, err := ReadFiles([]string{filepath.Join(dir, "conf.d"), single})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sources).To(Equal([]Source{
+				{Name: filepath.Join(dir, "conf.d", "10-a.yaml"), Data: []byte("a")},
+				{Name: filepath.Join(dir, "conf.d", "20-b.yml"), Data: []byte("b")},
+				{Name: filepath.Join(dir, "conf.d", "30-c.json"), Data: []byte("c")},
+				{Name: single, Data: []byte("single")},
+			}))
+		})
+
+		It("should fail if a directory contains no configuration files", func() {
+			_, err := ReadFiles([]string{dir})
+			Expect(err).To(MatchError(ContainSubstring("no configuration files found")))
+		})
+
+		It("should fail if a file does not exist", func() {
+			_, err := ReadFiles([]string{filepath.Join(dir, "missing.yaml")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#Reload", func() {
+		var (
+			store    *configutils.Store
+			reloader *Reloader
+		)
+
+		BeforeEach(func() {
+			store = configutils.NewStore(&v1alpha1.Configuration{})
+			reloader = NewReloader(logr.Discard(), store)
+		})
+
+		It("should merge the fragments in order", func() {
+			write("10-a.yaml", validConfig)
+			write("20-b.yaml", otherConfig)
+
+			reloader.Reload(ReadFiles([]string{dir}))
+			Expect(reloader.Err()).To(Succeed())
+			Expect(store.Get().Overwrites).To(Equal([]v1alpha1.ImageOverwrite{
+				{
+					Source:  v1alpha1.Image{Prefix: ptr.To("registry.example.com")},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com")}, Provider: "local"}},
+				},
+				{
+					Source:  v1alpha1.Image{Prefix: ptr.To("other.example.com")},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com")}, Provider: "local"}},
+				},
+			}))
+		})
+
+		It("should report conflicts between fragments with their sources", func() {
+			write("10-a.yaml", validConfig)
+			write("20-b.yaml", `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    prefix: registry.example.com
+  targets:
+  - prefix: other-mirror.example.com
+    provider: local
+`)
+
+			reloader.Reload(ReadFiles([]string{dir}))
+			Expect(reloader.Err()).To(MatchError(And(
+				ContainSubstring("fragments["+filepath.Join(dir, "20-b.yaml")+"].overwrites[0].targets[0]"),
+				ContainSubstring("conflicts with target \"mirror.example.com\" in fragments["+filepath.Join(dir, "10-a.yaml")+"]"),
+			)))
+			Expect(store.Get().Overwrites).To(BeEmpty())
+		})
+
+		It("should name the source of a fragment which cannot be decoded", func() {
+			write("10-a.yaml", "overwrites: {")
+
+			reloader.Reload(ReadFiles([]string{dir}))
+			Expect(reloader.Err()).To(MatchError(ContainSubstring(filepath.Join(dir, "10-a.yaml"))))
+		})
+	})
+
+	Describe("#LintFiles", func() {
+		It("should return the warnings of a valid configuration", func() {
+			write("10-a.yaml", `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    prefix: registry.example.com
+  targets:
+  - prefix: mirror.example.com
+    provider: local
+`)
+			write("20-b.yaml", `apiVersion: config.image-rewriter.extensions.gardener.cloud/v1alpha1
+kind: Configuration
+overwrites:
+- source:
+    image: registry.example.com/foo:1.0
+  targets:
+  - image: mirror.example.com/foo:1.0
+    provider: local
+`)
+
+			errs, warnings, err := LintFiles([]string{dir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errs).To(BeEmpty())
+			Expect(warnings).To(ConsistOf(HaveField("Field", "overwrites[1].targets[0]")))
+		})
+
+		It("should return the validation errors of an invalid configuration", func() {
+			write("config.yaml", invalidConfig)
+
+			errs, warnings, err := LintFiles([]string{dir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errs).To(ConsistOf(HaveField("Field", "overwrites[0].targets")))
+			Expect(warnings).To(BeEmpty())
+		})
+	})
+})
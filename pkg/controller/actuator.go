@@ -14,24 +14,33 @@ import (
 	"github.com/gardener/gardener/extensions/pkg/webhook"
 	"github.com/gardener/gardener/extensions/pkg/webhook/shoot"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	resourcesv1alpha1 "github.com/gardener/gardener/pkg/apis/resources/v1alpha1"
 	"github.com/gardener/gardener/pkg/utils/managedresources"
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/event"
 )
 
 type actuator struct {
-	client client.Client
+	client   client.Client
+	recorder events.EventRecorder
 
 	shootWebhookConfig *atomic.Value
-	config             image.Configuration
+	config             *configutils.Store
 }
 
 // NewActuator returns an actuator responsible for registry-cache Extension resources.
-func NewActuator(client client.Client, shootWebhookConfig *atomic.Value, config image.Configuration) extension.Actuator {
+func NewActuator(client client.Client, recorder events.EventRecorder, shootWebhookConfig *atomic.Value, config *configutils.Store) extension.Actuator {
 	return &actuator{
 		client:             client,
+		recorder:           recorder,
 		shootWebhookConfig: shootWebhookConfig,
 		config:             config,
 	}
@@ -41,18 +50,97 @@ func NewActuator(client client.Client, shootWebhookConfig *atomic.Value, config
 const ShootWebhooksResourceName = "extension-image-rewriter-shoot-webhooks"
 
 // Reconcile reconciles the Extension resource. It creates or deletes the shoot webhook configuration, depending on whether an overwrite configuration exists for the shoot's provider and region.
+// The shoot specific configuration of the Extension's provider config is merged on top of the global configuration.
+// The effective configuration for the shoot's provider and region is published in the provider status of the Extension.
 func (a *actuator) Reconcile(ctx context.Context, log logr.Logger, e *extensionsv1alpha1.Extension) error {
 	cluster, err := extensionscontroller.GetCluster(ctx, a.client, e.Namespace)
 	if client.IgnoreNotFound(err) != nil {
 		return err
 	}
 
-	if !a.config.HasOverwrite(cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region) {
+	config, imageConfig, err := a.config.ForExtension(e)
+	if err != nil {
+		return err
+	}
+
+	var (
+		shootProvider = cluster.Shoot.Spec.Provider.Type
+		shootRegion   = cluster.Shoot.Spec.Region
+	)
+
+	shootWebhooksInstalled := imageConfig.HasOverwrite(shootProvider, shootRegion)
+	if shootWebhooksInstalled {
+		if err := a.reconcileShootWebhookConfig(ctx, cluster); err != nil {
+			return err
+		}
+	} else {
 		log.Info("No overwrite configuration found for shoot provider and region")
-		return a.Delete(ctx, log, e)
+		removed, err := a.deleteShootWebhookConfig(ctx, log, e.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if removed {
+			a.recorder.Eventf(e, nil, corev1.EventTypeNormal, event.ReasonShootWebhooksRemoved, event.ActionReconcile,
+				"Removed shoot webhooks because no image overwrite is configured for provider %q and region %q", shootProvider, shootRegion)
+		}
+	}
+
+	previousFingerprint := StatusFingerprint(e)
+	status := EffectiveStatus(config, shootProvider, shootRegion, shootWebhooksInstalled)
+
+	// The OperatingSystemConfigs are mutated by the webhooks only when they are written. They are updated before the
+	// status, so that they are updated again if the update fails. There is nothing to do if the Extension is reconciled
+	// the first time.
+	configChanged := previousFingerprint != "" && previousFingerprint != status.ConfigFingerprint
+	if configChanged {
+		log.Info("Effective configuration changed, updating OperatingSystemConfigs")
+		if err := a.updateOperatingSystemConfigs(ctx, e.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if err := a.updateStatus(ctx, e, status); err != nil {
+		return err
+	}
+
+	if configChanged {
+		a.recorder.Eventf(e, nil, corev1.EventTypeNormal, event.ReasonConfigurationChanged, event.ActionReconcile,
+			"Effective configuration changed, updated the OperatingSystemConfigs to apply it to the nodes")
+	}
+
+	return nil
+}
+
+// updateOperatingSystemConfigs updates the OperatingSystemConfigs of the shoot with an empty patch. The update invokes
+// the webhooks, which mutate the OperatingSystemConfigs according to the current configuration. If they change, the
+// operating system extension reconciles them and gardener-node-agent applies the change to the nodes.
+func (a *actuator) updateOperatingSystemConfigs(ctx context.Context, namespace string) error {
+	oscList := &extensionsv1alpha1.OperatingSystemConfigList{}
+	if err := a.client.List(ctx, oscList, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list operating system configs: %w", err)
+	}
+
+	for _, osc := range oscList.Items {
+		if osc.DeletionTimestamp != nil {
+			continue
+		}
+
+		if err := a.client.Patch(ctx, &osc, client.RawPatch(types.MergePatchType, []byte("{}"))); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to update operating system config %s: %w", client.ObjectKeyFromObject(&osc), err)
+		}
 	}
 
-	return a.reconcileShootWebhookConfig(ctx, cluster)
+	return nil
+}
+
+func (a *actuator) updateStatus(ctx context.Context, e *extensionsv1alpha1.Extension, status *imagerewriterv1alpha1.ImageRewriterStatus) error {
+	patch := client.MergeFrom(e.DeepCopy())
+	e.Status.ProviderStatus = &runtime.RawExtension{Object: status}
+	if err := a.client.Status().Patch(ctx, e, patch); err != nil {
+		return fmt.Errorf("failed to update extension status: %w", err)
+	}
+	return nil
 }
 
 func (a *actuator) reconcileShootWebhookConfig(ctx context.Context, cluster *extensionscontroller.Cluster) error {
@@ -69,6 +157,20 @@ func (a *actuator) reconcileShootWebhookConfig(ctx context.Context, cluster *ext
 	return nil
 }
 
+// deleteShootWebhookConfig deletes the managed resource of the shoot webhooks. It returns true if the managed resource
+// existed before.
+func (a *actuator) deleteShootWebhookConfig(ctx context.Context, log logr.Logger, namespace string) (bool, error) {
+	if err := a.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ShootWebhooksResourceName}, &resourcesv1alpha1.ManagedResource{}); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return false, fmt.Errorf("failed to get managed resource of shoot webhooks: %w", err)
+		}
+		return false, nil
+	}
+
+	log.Info("Deleting Shoot webhook configuration")
+	return true, managedresources.DeleteForShoot(ctx, a.client, namespace, ShootWebhooksResourceName)
+}
+
 // Delete deletes the Extension resource.
 func (a *actuator) Delete(ctx context.Context, log logr.Logger, e *extensionsv1alpha1.Extension) error {
 	log.Info("Deleting Shoot webhook configuration")
@@ -6,19 +6,20 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"sync/atomic"
 
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	"github.com/gardener/gardener/extensions/pkg/controller/extension"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 )
 
 const (
 	// Type is the type of Extension resource.
-	Type = "image-rewriter"
+	Type = configutils.ExtensionType
 	// ControllerName is the name of the image rewriter controller.
 	ControllerName = "image-rewriter-controller"
 	// FinalizerSuffix is the finalizer suffix for the image rewriter controller.
@@ -35,20 +36,27 @@ type AddOptions struct {
 	// Controller contains options for the controller.
 	Controller controller.Options
 	// Config is the configuration for the image rewriter extension.
-	Config v1alpha1.Configuration
+	Config *configutils.Store
 	// ShootWebhookConfig holds the current Shoot webhook configuration.
 	ShootWebhookConfig *atomic.Value
 }
 
 // AddToManager adds the extension controller with the default Options to the given Controller Manager.
+// Extensions are reconciled again if the effective configuration for their shoot changes.
 func AddToManager(ctx context.Context, mgr manager.Manager) error {
+	requeuer := newRequeuer(mgr.GetLogger().WithName(ControllerName).WithName("requeuer"), mgr.GetClient(), DefaultAddOptions.Config)
+	if err := mgr.Add(requeuer); err != nil {
+		return fmt.Errorf("failed to add requeuer to manager: %w", err)
+	}
+
 	return extension.Add(mgr, extension.AddArgs{
-		Actuator:          NewActuator(mgr.GetClient(), DefaultAddOptions.ShootWebhookConfig, image.NewImageConfiguration(&DefaultAddOptions.Config)),
+		Actuator:          NewActuator(mgr.GetClient(), mgr.GetEventRecorder(ControllerName), DefaultAddOptions.ShootWebhookConfig, DefaultAddOptions.Config),
 		ControllerOptions: DefaultAddOptions.Controller,
 		Name:              ControllerName,
 		FinalizerSuffix:   FinalizerSuffix,
 		Resync:            0,
 		Predicates:        extension.DefaultPredicates(ctx, mgr, false),
 		Type:              Type,
+		WatchBuilder:      extensionscontroller.NewWatchBuilder(requeuer.watch),
 	})
 }
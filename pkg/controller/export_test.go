@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+// RequeueOutdatedExtensions returns the keys of the Extensions whose configuration is outdated and which are requeued.
+func RequeueOutdatedExtensions(ctx context.Context, c client.Client, config *configutils.Store) ([]client.ObjectKey, error) {
+	r := newRequeuer(logr.Discard(), c, config)
+	if err := r.requeueOutdatedExtensions(ctx); err != nil {
+		return nil, err
+	}
+	close(r.extensions)
+
+	var keys []client.ObjectKey
+	for e := range r.extensions {
+		keys = append(keys, client.ObjectKeyFromObject(e.Object))
+	}
+	return keys, nil
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+// requeuer triggers the reconciliation of the Extensions whose effective configuration differs from the configuration
+// they were reconciled with. It checks the Extensions on start and whenever the configuration is replaced. The
+// Extensions are enqueued through a channel source of the controller, see watch, they are not annotated.
+type requeuer struct {
+	log    logr.Logger
+	client client.Client
+	config *configutils.Store
+
+	extensions chan ctrlevent.GenericEvent
+}
+
+var _ manager.Runnable = (*requeuer)(nil)
+
+func newRequeuer(log logr.Logger, client client.Client, config *configutils.Store) *requeuer {
+	return &requeuer{
+		log:        log,
+		client:     client,
+		config:     config,
+		extensions: make(chan ctrlevent.GenericEvent, 100),
+	}
+}
+
+// Start implements manager.Runnable.
+func (r *requeuer) Start(ctx context.Context) error {
+	for {
+		changed := r.config.Changed()
+		if err := r.requeueOutdatedExtensions(ctx); err != nil {
+			r.log.Error(err, "Failed to trigger reconciliation of Extensions with outdated configuration")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// watch adds the source of the Extensions which are requeued to the given controller.
+func (r *requeuer) watch(ctrl controller.Controller) error {
+	return ctrl.Watch(source.Channel(r.extensions, &handler.EnqueueRequestForObject{}))
+}
+
+func (r *requeuer) requeueOutdatedExtensions(ctx context.Context) error {
+	extensionList := &extensionsv1alpha1.ExtensionList{}
+	if err := r.client.List(ctx, extensionList); err != nil {
+		return fmt.Errorf("failed to list extensions: %w", err)
+	}
+
+	for _, e := range extensionList.Items {
+		if e.Spec.Type != Type || e.DeletionTimestamp != nil {
+			continue
+		}
+
+		log := r.log.WithValues("extension", client.ObjectKeyFromObject(&e))
+
+		outdated, err := r.isOutdated(ctx, &e)
+		if err != nil {
+			log.Error(err, "Failed to determine whether the configuration of the Extension is outdated")
+			continue
+		}
+		if !outdated {
+			continue
+		}
+
+		log.Info("Effective configuration changed, triggering reconciliation of Extension")
+		select {
+		case r.extensions <- ctrlevent.GenericEvent{Object: e.DeepCopy()}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (r *requeuer) isOutdated(ctx context.Context, e *extensionsv1alpha1.Extension) (bool, error) {
+	cluster, err := extensionscontroller.GetCluster(ctx, r.client, e.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("failed to get cluster: %w", err)
+	}
+	if cluster.Shoot == nil {
+		return false, nil
+	}
+
+	config, err := configutils.ForExtension(r.config.Get(), e)
+	if err != nil {
+		return false, err
+	}
+
+	return StatusFingerprint(e) != Fingerprint(config, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region), nil
+}
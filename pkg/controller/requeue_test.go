@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller_test
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/controller"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+var _ = Describe("Requeue", func() {
+	var (
+		ctx        = context.Background()
+		namespace  = "shoot--test--local"
+		fakeClient client.Client
+		config     *v1alpha1.Configuration
+		store      *configutils.Store
+		extension  *extensionsv1alpha1.Extension
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		fakeClient = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+
+		config = &v1alpha1.Configuration{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com")}, Provider: "local"},
+					},
+				},
+			},
+		}
+		store = configutils.NewStore(config)
+
+		Expect(fakeClient.Create(ctx, &extensionsv1alpha1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			Spec: extensionsv1alpha1.ClusterSpec{
+				Shoot: runtime.RawExtension{Raw: []byte(`{"apiVersion":"core.gardener.cloud/v1beta1","kind":"Shoot","spec":{"provider":{"type":"local"},"region":"north"}}`)},
+			},
+		})).To(Succeed())
+
+		extension = &extensionsv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Name: "image-rewriter", Namespace: namespace},
+			Spec: extensionsv1alpha1.ExtensionSpec{
+				DefaultSpec: extensionsv1alpha1.DefaultSpec{Type: "image-rewriter"},
+			},
+			Status: extensionsv1alpha1.ExtensionStatus{
+				DefaultStatus: extensionsv1alpha1.DefaultStatus{
+					ProviderStatus: &runtime.RawExtension{Object: &imagerewriterv1alpha1.ImageRewriterStatus{
+						ConfigFingerprint: Fingerprint(config, "local", "north"),
+					}},
+				},
+			},
+		}
+	})
+
+	Describe("#RequeueOutdatedExtensions", func() {
+		It("should not trigger the reconciliation if the configuration is up to date", func() {
+			Expect(fakeClient.Create(ctx, extension)).To(Succeed())
+
+			Expect(RequeueOutdatedExtensions(ctx, fakeClient, store)).To(BeEmpty())
+		})
+
+		It("should trigger the reconciliation if the configuration changed", func() {
+			Expect(fakeClient.Create(ctx, extension)).To(Succeed())
+
+			store.Set(&v1alpha1.Configuration{})
+			Expect(RequeueOutdatedExtensions(ctx, fakeClient, store)).To(ConsistOf(client.ObjectKeyFromObject(extension)))
+
+			Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(extension), extension)).To(Succeed())
+			Expect(extension.Annotations).NotTo(HaveKey("gardener.cloud/operation"))
+		})
+
+		It("should trigger the reconciliation if the Extension has no fingerprint", func() {
+			extension.Status.ProviderStatus = nil
+			Expect(fakeClient.Create(ctx, extension)).To(Succeed())
+
+			Expect(RequeueOutdatedExtensions(ctx, fakeClient, store)).To(ConsistOf(client.ObjectKeyFromObject(extension)))
+		})
+
+		It("should ignore Extensions of other types", func() {
+			extension.Spec.Type = "other"
+			Expect(fakeClient.Create(ctx, extension)).To(Succeed())
+
+			store.Set(&v1alpha1.Configuration{})
+			Expect(RequeueOutdatedExtensions(ctx, fakeClient, store)).To(BeEmpty())
+		})
+	})
+})
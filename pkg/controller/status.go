@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/containerd"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+// EffectiveStatus returns the status with the overwrites and containerd upstreams of the configuration which apply to
+// the given provider and region.
+func EffectiveStatus(config *v1alpha1.Configuration, provider, region string, shootWebhooksInstalled bool) *imagerewriterv1alpha1.ImageRewriterStatus {
+	status := &imagerewriterv1alpha1.ImageRewriterStatus{
+		ShootWebhooksInstalled: shootWebhooksInstalled,
+		ConfigFingerprint:      Fingerprint(config, provider, region),
+	}
+	status.SetGroupVersionKind(imagerewriterv1alpha1.SchemeGroupVersion.WithKind("ImageRewriterStatus"))
+
+	for _, overwrite := range image.NewImageConfiguration(config).Overwrites(provider, region) {
+		status.Overwrites = append(status.Overwrites, imagerewriterv1alpha1.EffectiveOverwrite{
+			Source: overwrite.Source,
+			Target: overwrite.Target,
+		})
+	}
+
+	for _, upstreamConfig := range containerd.NewConfiguration(config).GetUpstreamConfig(provider, region) {
+		upstream := imagerewriterv1alpha1.EffectiveContainerdUpstream{
+			Upstream: upstreamConfig.Upstream,
+			Server:   upstreamConfig.Server,
+		}
+		for _, host := range upstreamConfig.Hosts {
+			upstream.Hosts = append(upstream.Hosts, host.URL)
+		}
+		status.Containerd = append(status.Containerd, upstream)
+	}
+
+	return status
+}
+
+// Fingerprint returns the fingerprint of the overwrites and containerd upstreams of the configuration which apply to the
+// given provider and region. It changes whenever the webhooks mutate the objects of a shoot with this provider and
+// region differently.
+func Fingerprint(config *v1alpha1.Configuration, provider, region string) string {
+	// Marshalling cannot fail, the values consist of strings, booleans and pointers to them only.
+	data, _ := json.Marshal(struct {
+		Overwrites []image.Overwrite
+		Containerd []containerd.UpStreamConfiguration
+	}{
+		Overwrites: image.NewImageConfiguration(config).Overwrites(provider, region),
+		Containerd: containerd.NewConfiguration(config).GetUpstreamConfig(provider, region),
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// StatusFingerprint returns the fingerprint of the configuration which was applied when the given Extension was
+// reconciled the last time. It returns an empty string if the Extension has not been reconciled yet.
+func StatusFingerprint(e *extensionsv1alpha1.Extension) string {
+	if e.Status.ProviderStatus == nil {
+		return ""
+	}
+
+	if status, ok := e.Status.ProviderStatus.Object.(*imagerewriterv1alpha1.ImageRewriterStatus); ok {
+		return status.ConfigFingerprint
+	}
+
+	status := &imagerewriterv1alpha1.ImageRewriterStatus{}
+	if err := json.Unmarshal(e.Status.ProviderStatus.Raw, status); err != nil {
+		return ""
+	}
+	return status.ConfigFingerprint
+}
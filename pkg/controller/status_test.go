@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller_test
+
+import (
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/controller"
+)
+
+var _ = Describe("Status", func() {
+	var config *v1alpha1.Configuration
+
+	BeforeEach(func() {
+		config = &v1alpha1.Configuration{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To("registry.example.com/image:latest")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Image: ptr.To("west.example.com/image:latest")}, Provider: "local", Regions: []string{"west"}},
+						{Image: v1alpha1.Image{Image: ptr.To("local.example.com/image:latest")}, Provider: "local"},
+					},
+				},
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com/prefix")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Prefix: ptr.To("east.example.com/prefix")}, Provider: "local", Regions: []string{"east"}},
+					},
+				},
+			},
+			Containerd: []v1alpha1.ContainerdConfiguration{
+				{
+					Upstream: "registry.example.com",
+					Server:   "https://registry.example.com",
+					Hosts: []v1alpha1.ContainerdHostConfig{
+						{URL: "https://west.example.com", Provider: "local", Regions: []string{"west"}},
+					},
+				},
+			},
+		}
+	})
+
+	Describe("#EffectiveStatus", func() {
+		It("should return the overwrites and containerd upstreams for the provider and region", func() {
+			Expect(EffectiveStatus(config, "local", "west", true)).To(Equal(&imagerewriterv1alpha1.ImageRewriterStatus{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "image-rewriter.extensions.gardener.cloud/v1alpha1",
+					Kind:       "ImageRewriterStatus",
+				},
+				ShootWebhooksInstalled: true,
+				ConfigFingerprint:      Fingerprint(config, "local", "west"),
+				Overwrites: []imagerewriterv1alpha1.EffectiveOverwrite{
+					{
+						Source: v1alpha1.Image{Image: ptr.To("registry.example.com/image:latest")},
+						Target: v1alpha1.Image{Image: ptr.To("west.example.com/image:latest")},
+					},
+				},
+				Containerd: []imagerewriterv1alpha1.EffectiveContainerdUpstream{
+					{
+						Upstream: "registry.example.com",
+						Server:   "https://registry.example.com",
+						Hosts:    []string{"https://west.example.com"},
+					},
+				},
+			}))
+		})
+
+		It("should fall back to the target for any region of the provider", func() {
+			status := EffectiveStatus(config, "local", "east", true)
+			Expect(status.Overwrites).To(Equal([]imagerewriterv1alpha1.EffectiveOverwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To("registry.example.com/image:latest")},
+					Target: v1alpha1.Image{Image: ptr.To("local.example.com/image:latest")},
+				},
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com/prefix")},
+					Target: v1alpha1.Image{Prefix: ptr.To("east.example.com/prefix")},
+				},
+			}))
+			Expect(status.Containerd).To(BeEmpty())
+		})
+
+		It("should return an empty status if nothing applies to the provider", func() {
+			status := EffectiveStatus(config, "other", "west", false)
+			Expect(status.ShootWebhooksInstalled).To(BeFalse())
+			Expect(status.Overwrites).To(BeEmpty())
+			Expect(status.Containerd).To(BeEmpty())
+		})
+	})
+
+	Describe("#Fingerprint", func() {
+		It("should only change if the effective configuration changes", func() {
+			fingerprint := Fingerprint(config, "local", "west")
+			Expect(fingerprint).To(HaveLen(64))
+			Expect(Fingerprint(config, "local", "west")).To(Equal(fingerprint))
+			Expect(Fingerprint(config, "local", "east")).NotTo(Equal(fingerprint))
+
+			config.Overwrites[1].Targets[0].Image.Prefix = ptr.To("other.example.com/prefix")
+			Expect(Fingerprint(config, "local", "west")).To(Equal(fingerprint))
+
+			config.Containerd[0].Hosts[0].URL = "https://other.example.com"
+			Expect(Fingerprint(config, "local", "west")).NotTo(Equal(fingerprint))
+		})
+
+		It("should not change if upstreams for other providers are added", func() {
+			fingerprint := Fingerprint(config, "local", "west")
+
+			config.Containerd = append([]v1alpha1.ContainerdConfiguration{{
+				Upstream: "other.example.com",
+				Server:   "https://other.example.com",
+				Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://mirror.example.com", Provider: "other"}},
+			}}, config.Containerd...)
+			Expect(Fingerprint(config, "local", "west")).To(Equal(fingerprint))
+		})
+	})
+
+	Describe("#StatusFingerprint", func() {
+		It("should return the fingerprint of the provider status", func() {
+			extension := &extensionsv1alpha1.Extension{}
+			Expect(StatusFingerprint(extension)).To(BeEmpty())
+
+			extension.Status.ProviderStatus = &runtime.RawExtension{Object: &imagerewriterv1alpha1.ImageRewriterStatus{ConfigFingerprint: "foo"}}
+			Expect(StatusFingerprint(extension)).To(Equal("foo"))
+
+			extension.Status.ProviderStatus = &runtime.RawExtension{Raw: []byte(`{"apiVersion":"image-rewriter.extensions.gardener.cloud/v1alpha1","kind":"ImageRewriterStatus","configFingerprint":"bar"}`)}
+			Expect(StatusFingerprint(extension)).To(Equal("bar"))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+const namespace = "image_rewriter"
+
+var (
+	// ImagesRewritten counts the images which were rewritten. The rule identifies the matching overwrite of the global
+	// configuration, see RuleLabel. For the 'osc-containerd' webhook, it counts the added upstream configurations and
+	// the rule identifies the upstream, see UpstreamLabel.
+	ImagesRewritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "images_rewritten_total",
+		Help:      "Number of images which were rewritten.",
+	}, []string{"webhook", "provider", "region", "rule"})
+
+	// ImagesUnchanged counts the images which matched a source rule but were left unchanged because the rule has no
+	// target for the provider and region. For the 'osc-containerd' webhook, it counts the upstream configurations
+	// which were not added because the upstream is already configured.
+	ImagesUnchanged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "images_unchanged_total",
+		Help:      "Number of images which matched a source rule but were left unchanged.",
+	}, []string{"webhook", "provider", "region", "rule"})
+
+	// LookupMisses counts the images which did not match any source rule.
+	LookupMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lookup_misses_total",
+		Help:      "Number of images which did not match any source rule.",
+	}, []string{"webhook", "provider", "region"})
+
+	// MutateDuration observes the duration of the mutations of the webhooks.
+	MutateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mutate_duration_seconds",
+		Help:      "Duration of the mutations in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"webhook"})
+
+	// ConfigReloads counts the reloads of the configuration file by their result, i.e. 'success' or 'failure'.
+	ConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Number of reloads of the configuration file.",
+	}, []string{"result"})
+
+	// ConfigLastReloadSuccessful is 1 if the last reload of the configuration file succeeded and 0 otherwise.
+	ConfigLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last reload of the configuration file succeeded.",
+	})
+
+	// ConfigLastReloadSuccessTimestamp is the time of the last successful reload of the configuration file.
+	ConfigLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful reload of the configuration file.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(ImagesRewritten, ImagesUnchanged, LookupMisses, MutateDuration,
+		ConfigReloads, ConfigLastReloadSuccessful, ConfigLastReloadSuccessTimestamp)
+}
+
+// RecordLookup records the result of an image lookup of the given webhook. The rule label refers to the given global
+// configuration, see RuleLabel.
+func RecordLookup(webhook, provider, region string, global *v1alpha1.Configuration, result image.Result) {
+	switch {
+	case result.Target != "":
+		ImagesRewritten.WithLabelValues(webhook, provider, region, RuleLabel(global, result.RuleSource)).Inc()
+	case result.Rule != "":
+		ImagesUnchanged.WithLabelValues(webhook, provider, region, RuleLabel(global, result.RuleSource)).Inc()
+	default:
+		LookupMisses.WithLabelValues(webhook, provider, region).Inc()
+	}
+}
+
+// RuleLabel returns the value of the rule label for the overwrite with the given source. It is 'global/<index>' with the
+// index of the overwrite with the same source in the global configuration, hence it is the same for all shoots. Overwrites
+// which are only configured for a shoot are labelled 'shoot'. The configured sources are not used as label values, they
+// may be arbitrary strings of shoot owners.
+func RuleLabel(global *v1alpha1.Configuration, source v1alpha1.Image) string {
+	for i, overwrite := range global.Overwrites {
+		if ptr.Equal(overwrite.Source.Image, source.Image) && ptr.Equal(overwrite.Source.Prefix, source.Prefix) && ptr.Equal(overwrite.Source.Regex, source.Regex) {
+			return globalRuleLabel(i)
+		}
+	}
+	return shootRuleLabel
+}
+
+// UpstreamLabel returns the value of the rule label for the containerd upstream with the given name like RuleLabel, i.e.
+// 'global/<index>' with the index of the upstream in the global configuration or 'shoot'.
+func UpstreamLabel(global *v1alpha1.Configuration, upstream string) string {
+	for i, containerdConfig := range global.Containerd {
+		if containerdConfig.Upstream == upstream {
+			return globalRuleLabel(i)
+		}
+	}
+	return shootRuleLabel
+}
+
+const shootRuleLabel = "shoot"
+
+func globalRuleLabel(index int) string {
+	return "global/" + strconv.Itoa(index)
+}
+
+// ObserveMutateDuration records the duration of a mutation of the given webhook which started at the given time.
+func ObserveMutateDuration(webhook string, start time.Time) {
+	MutateDuration.WithLabelValues(webhook).Observe(time.Since(start).Seconds())
+}
+
+// RecordConfigReload records the result of a reload of the configuration file.
+func RecordConfigReload(err error) {
+	if err != nil {
+		ConfigReloads.WithLabelValues("failure").Inc()
+		ConfigLastReloadSuccessful.Set(0)
+		return
+	}
+
+	ConfigReloads.WithLabelValues("success").Inc()
+	ConfigLastReloadSuccessful.Set(1)
+	ConfigLastReloadSuccessTimestamp.SetToCurrentTime()
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+var _ = Describe("Metrics", func() {
+	var global *v1alpha1.Configuration
+
+	BeforeEach(func() {
+		global = &v1alpha1.Configuration{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")}},
+				{Source: v1alpha1.Image{Image: ptr.To("nginx")}},
+			},
+			Containerd: []v1alpha1.ContainerdConfiguration{
+				{Upstream: "docker.io"},
+				{Upstream: "ghcr.io"},
+			},
+		}
+
+		ImagesRewritten.Reset()
+		ImagesUnchanged.Reset()
+		LookupMisses.Reset()
+		MutateDuration.Reset()
+		ConfigReloads.Reset()
+	})
+
+	Describe("#RecordLookup", func() {
+		It("should count rewritten images", func() {
+			RecordLookup("pod-image-rewriter", "local", "north", global, image.Result{Target: "mirror.example/nginx:latest", Rule: "nginx", RuleSource: v1alpha1.Image{Image: ptr.To("nginx")}})
+
+			Expect(testutil.ToFloat64(ImagesRewritten.WithLabelValues("pod-image-rewriter", "local", "north", "global/1"))).To(Equal(1.0))
+			Expect(testutil.CollectAndCount(ImagesUnchanged)).To(Equal(0))
+			Expect(testutil.CollectAndCount(LookupMisses)).To(Equal(0))
+		})
+
+		It("should count unchanged images", func() {
+			RecordLookup("osc-image-rewriter", "local", "south", global, image.Result{Rule: "nginx", RuleSource: v1alpha1.Image{Image: ptr.To("nginx")}})
+
+			Expect(testutil.ToFloat64(ImagesUnchanged.WithLabelValues("osc-image-rewriter", "local", "south", "global/1"))).To(Equal(1.0))
+			Expect(testutil.CollectAndCount(ImagesRewritten)).To(Equal(0))
+			Expect(testutil.CollectAndCount(LookupMisses)).To(Equal(0))
+		})
+
+		It("should count lookup misses", func() {
+			RecordLookup("pod-image-rewriter", "local", "north", global, image.Result{})
+			RecordLookup("pod-image-rewriter", "local", "north", global, image.Result{})
+
+			Expect(testutil.ToFloat64(LookupMisses.WithLabelValues("pod-image-rewriter", "local", "north"))).To(Equal(2.0))
+			Expect(testutil.CollectAndCount(ImagesRewritten)).To(Equal(0))
+			Expect(testutil.CollectAndCount(ImagesUnchanged)).To(Equal(0))
+		})
+	})
+
+	Describe("#RuleLabel", func() {
+		It("should return the index of the overwrite in the global configuration", func() {
+			Expect(RuleLabel(global, v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")})).To(Equal("global/0"))
+			Expect(RuleLabel(global, v1alpha1.Image{Image: ptr.To("nginx")})).To(Equal("global/1"))
+		})
+
+		It("should label overwrites which are only configured for the shoot", func() {
+			Expect(RuleLabel(global, v1alpha1.Image{Image: ptr.To("registry.k8s.io")})).To(Equal("shoot"))
+			Expect(RuleLabel(global, v1alpha1.Image{Prefix: ptr.To("quay.io")})).To(Equal("shoot"))
+		})
+	})
+
+	Describe("#UpstreamLabel", func() {
+		It("should return the index of the upstream in the global configuration", func() {
+			Expect(UpstreamLabel(global, "ghcr.io")).To(Equal("global/1"))
+		})
+
+		It("should label upstreams which are only configured for the shoot", func() {
+			Expect(UpstreamLabel(global, "quay.io")).To(Equal("shoot"))
+		})
+	})
+
+	Describe("#ObserveMutateDuration", func() {
+		It("should observe the duration of the mutation", func() {
+			ObserveMutateDuration("osc-containerd", time.Now().Add(-time.Second))
+
+			Expect(testutil.CollectAndCount(MutateDuration)).To(Equal(1))
+		})
+	})
+
+	Describe("#RecordConfigReload", func() {
+		It("should record a successful reload", func() {
+			RecordConfigReload(nil)
+
+			Expect(testutil.ToFloat64(ConfigReloads.WithLabelValues("success"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(ConfigLastReloadSuccessful)).To(Equal(1.0))
+			Expect(testutil.ToFloat64(ConfigLastReloadSuccessTimestamp)).To(BeNumerically("~", time.Now().Unix(), 5))
+		})
+
+		It("should record a failed reload", func() {
+			RecordConfigReload(errors.New("invalid configuration"))
+
+			Expect(testutil.ToFloat64(ConfigReloads.WithLabelValues("failure"))).To(Equal(1.0))
+			Expect(testutil.ToFloat64(ConfigLastReloadSuccessful)).To(Equal(0.0))
+		})
+	})
+})
@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
+	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/pmezard/go-difflib/difflib"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	kubernetesscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+	containerdwebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/containerd"
+	imagewebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/image"
+	podwebhook "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/pod"
+)
+
+// DefaultNamespace is the seed namespace of the fake shoot. It is used for pods and workload resources and for
+// OperatingSystemConfigs without namespace.
+const DefaultNamespace = "shoot--local--offline"
+
+var (
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+)
+
+func init() {
+	utilruntime.Must(kubernetesscheme.AddToScheme(scheme))
+	utilruntime.Must(extensionsv1alpha1.AddToScheme(scheme))
+}
+
+// Mutator runs the webhook mutators of the extension against objects of a fake shoot without a seed.
+type Mutator struct {
+	client     client.Client
+	provider   string
+	region     string
+	podWebhook *v1alpha1.PodWebhookConfiguration
+	// namespaces are the labels of the namespaces of the fake shoot which were passed in the manifests.
+	namespaces map[string]labels.Set
+	// warnings are the warnings about objects whose mutation may differ from the webhooks, see Warnings.
+	warnings []string
+
+	operatingSystemConfigMutators []extensionswebhook.Mutator
+	podMutator                    extensionswebhook.Mutator
+}
+
+// NewMutator creates a new Mutator for a shoot of the given provider and region. Namespaces of the shoot cluster, whose
+// labels are evaluated by the namespace selector of the pod webhook, can be passed in the manifests before their pods.
+func NewMutator(config *v1alpha1.Configuration, provider, region string) *Mutator {
+	var (
+		store    = configutils.NewStore(config)
+		c        = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+		recorder = &events.FakeRecorder{}
+	)
+
+	return &Mutator{
+		client:     c,
+		provider:   provider,
+		region:     region,
+		podWebhook: config.PodWebhook,
+		namespaces: map[string]labels.Set{},
+		// The order is the order in which the webhooks are registered, see cmd.WebhookSwitchOptions.
+		operatingSystemConfigMutators: []extensionswebhook.Mutator{
+			imagewebhook.NewMutator(c, recorder, store),
+			containerdwebhook.NewMutator(c, recorder, store),
+		},
+		podMutator: podwebhook.NewMutator(c, store),
+	}
+}
+
+// Mutate mutates the given object in place. OperatingSystemConfigs are mutated by the OperatingSystemConfig webhooks,
+// pods and workload resources by the pod webhook if it selects them, see podWebhookSelects. Namespaces are not mutated
+// but their labels are recorded.
+func (m *Mutator) Mutate(ctx context.Context, obj client.Object) error {
+	switch obj := obj.(type) {
+	case *extensionsv1alpha1.OperatingSystemConfig:
+		namespace := obj.Namespace
+		if namespace == "" {
+			obj.Namespace = DefaultNamespace
+			defer func() { obj.Namespace = namespace }()
+		}
+
+		if _, err := m.ensureCluster(ctx, obj.Namespace); err != nil {
+			return err
+		}
+
+		for _, mutator := range m.operatingSystemConfigMutators {
+			if err := mutator.Mutate(ctx, obj, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *corev1.Pod, *appsv1.Deployment, *appsv1.DaemonSet, *appsv1.StatefulSet, *batchv1.Job, *batchv1.CronJob:
+		if selected, err := m.podWebhookSelects(obj); err != nil || !selected {
+			return err
+		}
+
+		cluster, err := m.ensureCluster(ctx, DefaultNamespace)
+		if err != nil {
+			return err
+		}
+		return m.podMutator.Mutate(context.WithValue(ctx, extensionswebhook.ClusterObjectContextKey{}, cluster), obj, nil)
+
+	case *corev1.Namespace:
+		m.namespaces[obj.Name] = namespaceLabels(obj.Name, obj.Labels)
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported object %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+}
+
+// Warnings returns the warnings about objects whose mutation may differ from the mutation of the webhooks, e.g. because
+// the labels of their namespace are unknown.
+func (m *Mutator) Warnings() []string {
+	return m.warnings
+}
+
+// podWebhookSelects returns true if the pod webhook is called for the given pod or workload resource, i.e. if its kind
+// is registered and the namespace and object selectors match. Objects without namespace are in the 'default'
+// namespace. The namespace selector is evaluated against the labels of the namespace if it was passed in the manifests
+// before, otherwise against its name only and a warning is recorded if the namespace selector is configured.
+func (m *Mutator) podWebhookSelects(obj client.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return false, err
+	}
+	if _, isPod := obj.(*corev1.Pod); !isPod && (m.podWebhook == nil || !slices.Contains(m.podWebhook.WorkloadKinds, gvk.Kind)) {
+		return false, nil
+	}
+
+	namespaceName := obj.GetNamespace()
+	if namespaceName == "" {
+		namespaceName = metav1.NamespaceDefault
+	}
+	namespace, ok := m.namespaces[namespaceName]
+	if !ok {
+		namespace = namespaceLabels(namespaceName, nil)
+		if m.podWebhook != nil && m.podWebhook.NamespaceSelector != nil {
+			m.warnings = append(m.warnings, fmt.Sprintf("labels of namespace %q are unknown, the namespace selector of the pod webhook is evaluated against its name only; pass the namespace in the manifests before %s %s",
+				namespaceName, gvk.Kind, client.ObjectKeyFromObject(obj)))
+		}
+	}
+
+	namespaceSelector, err := metav1.LabelSelectorAsSelector(podwebhook.NamespaceSelector(m.podWebhook))
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector of pod webhook: %w", err)
+	}
+	if !namespaceSelector.Matches(namespace) {
+		return false, nil
+	}
+
+	if m.podWebhook == nil || m.podWebhook.ObjectSelector == nil {
+		return true, nil
+	}
+	objectSelector, err := metav1.LabelSelectorAsSelector(m.podWebhook.ObjectSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid object selector of pod webhook: %w", err)
+	}
+	return objectSelector.Matches(labels.Set(obj.GetLabels())), nil
+}
+
+// namespaceLabels returns the labels of a namespace including the name label, which the API server sets for every
+// namespace.
+func namespaceLabels(name string, namespaceLabels map[string]string) labels.Set {
+	set := labels.Set{corev1.LabelMetadataName: name}
+	for key, value := range namespaceLabels {
+		set[key] = value
+	}
+	return set
+}
+
+// MutateManifests decodes the objects of the given YAML or JSON stream, mutates them in order and returns the encoded
+// objects before and after the mutation. Both are encoded the same way, so that they can be compared.
+func (m *Mutator) MutateManifests(ctx context.Context, data []byte) ([]byte, []byte, error) {
+	objects, err := Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var original, mutated [][]byte
+	for _, obj := range objects {
+		before, err := sigsyaml.Marshal(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := m.Mutate(ctx, obj); err != nil {
+			return nil, nil, fmt.Errorf("failed to mutate %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, client.ObjectKeyFromObject(obj), err)
+		}
+
+		after, err := sigsyaml.Marshal(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		original, mutated = append(original, before), append(mutated, after)
+	}
+
+	separator := []byte("---\n")
+	return bytes.Join(original, separator), bytes.Join(mutated, separator), nil
+}
+
+func (m *Mutator) ensureCluster(ctx context.Context, namespace string) (*extensionscontroller.Cluster, error) {
+	shoot, err := json.Marshal(&gardencorev1beta1.Shoot{
+		TypeMeta: metav1.TypeMeta{APIVersion: gardencorev1beta1.SchemeGroupVersion.String(), Kind: "Shoot"},
+		Spec: gardencorev1beta1.ShootSpec{
+			Provider: gardencorev1beta1.Provider{Type: m.provider},
+			Region:   m.region,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := &extensionsv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		Spec:       extensionsv1alpha1.ClusterSpec{Shoot: runtime.RawExtension{Raw: shoot}},
+	}
+	if err := m.client.Create(ctx, cluster); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create cluster: %w", err)
+	}
+
+	return extensionscontroller.GetCluster(ctx, m.client, namespace)
+}
+
+// Decode decodes the objects of the given YAML or JSON stream. Empty documents are skipped.
+func Decode(data []byte) ([]client.Object, error) {
+	var (
+		objects []client.Object
+		decoder = yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	)
+
+	for {
+		raw := runtime.RawExtension{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+
+		obj, gvk, err := codecs.UniversalDeserializer().Decode(raw.Raw, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object %d: %w", len(objects), err)
+		}
+
+		clientObj, ok := obj.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object %s", gvk.Kind)
+		}
+		clientObj.GetObjectKind().SetGroupVersionKind(*gvk)
+		objects = append(objects, clientObj)
+	}
+}
+
+// Diff returns the unified diff of the given manifests. It is empty if the manifests are equal.
+func Diff(name string, original, mutated []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(original)),
+		B:        difflib.SplitLines(string(mutated)),
+		FromFile: name,
+		ToFile:   name + " (mutated)",
+		Context:  3,
+	})
+}
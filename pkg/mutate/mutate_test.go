@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mutate_test
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/mutate"
+)
+
+var _ = Describe("Mutator", func() {
+	var (
+		ctx     context.Context
+		mutator *Mutator
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		mutator = NewMutator(&v1alpha1.Configuration{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{
+					Source:  v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com/k8s")}, Provider: "aws", Regions: []string{"eu-west-1"}}},
+				},
+			},
+			Containerd: []v1alpha1.ContainerdConfiguration{
+				{
+					Upstream: "docker.io",
+					Server:   "https://registry-1.docker.io",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://mirror.example.com", Provider: "aws"}},
+				},
+			},
+		}, "aws", "eu-west-1")
+	})
+
+	Describe("#Mutate", func() {
+		It("should run the OperatingSystemConfig mutators", func() {
+			osc := &extensionsv1alpha1.OperatingSystemConfig{
+				Spec: extensionsv1alpha1.OperatingSystemConfigSpec{
+					Purpose: extensionsv1alpha1.OperatingSystemConfigPurposeReconcile,
+					CRIConfig: &extensionsv1alpha1.CRIConfig{
+						Name:       extensionsv1alpha1.CRINameContainerD,
+						Containerd: &extensionsv1alpha1.ContainerdConfig{SandboxImage: "registry.k8s.io/pause:3.10"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, osc)).To(Succeed())
+			Expect(osc.Namespace).To(BeEmpty())
+			Expect(osc.Spec.CRIConfig.Containerd.SandboxImage).To(Equal("mirror.example.com/k8s/pause:3.10"))
+			Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(HaveField("Upstream", "docker.io")))
+		})
+
+		Context("pod webhook", func() {
+			var config *v1alpha1.Configuration
+
+			newPod := func(namespace string, podLabels map[string]string) *corev1.Pod {
+				return &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace, Labels: podLabels},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "pause", Image: "registry.k8s.io/pause:3.10"}}},
+				}
+			}
+
+			BeforeEach(func() {
+				config = &v1alpha1.Configuration{
+					Overwrites: []v1alpha1.ImageOverwrite{
+						{
+							Source:  v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+							Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com/k8s")}, Provider: "aws"}},
+						},
+					},
+				}
+			})
+
+			It("should only mutate pods in the kube-system namespace by default", func() {
+				pod := newPod("kube-system", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("mirror.example.com/k8s/pause:3.10"))
+
+				pod = newPod("", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+				Expect(mutator.Warnings()).To(BeEmpty())
+			})
+
+			It("should respect the skip label of namespaces", func() {
+				Expect(mutator.Mutate(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "kube-system",
+					Labels: map[string]string{"image-rewriter.extensions.gardener.cloud/skip": "true"},
+				}})).To(Succeed())
+
+				pod := newPod("kube-system", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+			})
+
+			It("should evaluate the namespace selector against the labels of the namespaces", func() {
+				config.PodWebhook = &v1alpha1.PodWebhookConfiguration{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+				}
+				mutator = NewMutator(config, "aws", "eu-west-1")
+
+				Expect(mutator.Mutate(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform", Labels: map[string]string{"team": "platform"}}})).To(Succeed())
+				Expect(mutator.Mutate(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}})).To(Succeed())
+
+				pod := newPod("platform", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("mirror.example.com/k8s/pause:3.10"))
+
+				pod = newPod("other", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+				Expect(mutator.Warnings()).To(BeEmpty())
+			})
+
+			It("should warn if the labels of a namespace are unknown", func() {
+				config.PodWebhook = &v1alpha1.PodWebhookConfiguration{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+				}
+				mutator = NewMutator(config, "aws", "eu-west-1")
+
+				pod := newPod("unknown", nil)
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+				Expect(mutator.Warnings()).To(ConsistOf(ContainSubstring(`labels of namespace "unknown" are unknown`)))
+			})
+
+			It("should evaluate the object selector against the labels of the objects", func() {
+				config.PodWebhook = &v1alpha1.PodWebhookConfiguration{
+					ObjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "rewrite"}},
+					WorkloadKinds:  []string{"Deployment"},
+				}
+				mutator = NewMutator(config, "aws", "eu-west-1")
+
+				pod := newPod("kube-system", map[string]string{"app": "rewrite"})
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("mirror.example.com/k8s/pause:3.10"))
+
+				pod = newPod("kube-system", map[string]string{"app": "other"})
+				Expect(mutator.Mutate(ctx, pod)).To(Succeed())
+				Expect(pod.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+
+				deployment := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "kube-system", Labels: map[string]string{"app": "rewrite"}},
+					Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: newPod("", nil).Spec}},
+				}
+				Expect(mutator.Mutate(ctx, deployment)).To(Succeed())
+				Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("mirror.example.com/k8s/pause:3.10"))
+			})
+
+			It("should only mutate the registered workload kinds", func() {
+				daemonSet := &appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "kube-system"},
+					Spec:       appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: newPod("", nil).Spec}},
+				}
+				Expect(mutator.Mutate(ctx, daemonSet)).To(Succeed())
+				Expect(daemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.k8s.io/pause:3.10"))
+			})
+		})
+
+		It("should fail for unsupported objects", func() {
+			Expect(mutator.Mutate(ctx, &corev1.ConfigMap{})).To(MatchError(ContainSubstring("unsupported object")))
+		})
+	})
+
+	Describe("#MutateManifests", func() {
+		It("should return the encoded manifests before and after the mutation", func() {
+			original, mutated, err := mutator.MutateManifests(ctx, []byte(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+  namespace: kube-system
+spec:
+  containers:
+  - name: pause
+    image: registry.k8s.io/pause:3.10
+---
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: unchanged
+  namespace: kube-system
+spec:
+  containers:
+  - name: nginx
+    image: nginx
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			diff, err := Diff("pods.yaml", original, mutated)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diff).To(Equal(`--- pods.yaml
++++ pods.yaml (mutated)
+@@ -1,11 +1,13 @@
+ apiVersion: v1
+ kind: Pod
+ metadata:
++  annotations:
++    image-rewriter.extensions.gardener.cloud/original-images: '{"pause":"registry.k8s.io/pause:3.10"}'
+   name: test
+   namespace: kube-system
+ spec:
+   containers:
+-  - image: registry.k8s.io/pause:3.10
++  - image: mirror.example.com/k8s/pause:3.10
+     name: pause
+     resources: {}
+ status: {}
+`))
+		})
+
+		It("should fail for manifests which cannot be decoded", func() {
+			_, _, err := mutator.MutateManifests(ctx, []byte("apiVersion: v1\nkind: Unknown\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("#Diff", func() {
+		It("should return an empty diff for equal manifests", func() {
+			Expect(Diff("pods.yaml", []byte("a: b\n"), []byte("a: b\n"))).To(BeEmpty())
+		})
+	})
+})
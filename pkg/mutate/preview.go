@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mutate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/containerd"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+// ReadImages reads one image per line from the given reader. Empty lines and lines starting with '#' are ignored.
+func ReadImages(r io.Reader) ([]string, error) {
+	var images []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		images = append(images, line)
+	}
+
+	return images, scanner.Err()
+}
+
+// WritePreview writes the rewrites of the given images and the containerd hosts.toml files which the extension applies to
+// shoots of the given provider and region.
+func WritePreview(w io.Writer, config *v1alpha1.Configuration, provider, region string, images []string) error {
+	imageConfig := image.NewImageConfiguration(config)
+
+	for _, sourceImage := range images {
+		result := imageConfig.Lookup(sourceImage, provider, region)
+		switch {
+		case result.Target != "":
+			if _, err := fmt.Fprintf(w, "%s → %s\n", sourceImage, result.Target); err != nil {
+				return err
+			}
+		case result.Rule != "":
+			if _, err := fmt.Fprintf(w, "%s (unchanged, %s has no target for provider %q and region %q)\n", sourceImage, result.Rule, provider, region); err != nil {
+				return err
+			}
+		default:
+			if _, err := fmt.Fprintf(w, "%s (unchanged)\n", sourceImage); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, upstreamConfig := range containerd.NewConfiguration(config).GetUpstreamConfig(provider, region) {
+		mirror := containerd.RegistryMirror{
+			Upstream:       upstreamConfig.Upstream,
+			UpstreamServer: upstreamConfig.Server,
+			MirrorHosts:    upstreamConfig.Hosts,
+		}
+
+		data, err := mirror.HostsTOML()
+		if err != nil {
+			return fmt.Errorf("failed to create hosts.toml file for upstream %q: %w", upstreamConfig.Upstream, err)
+		}
+
+		if _, err := fmt.Fprintf(w, "\n# %s\n%s", containerd.HostsTOMLPath(upstreamConfig.Upstream), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
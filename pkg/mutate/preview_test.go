@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mutate_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/mutate"
+)
+
+var _ = Describe("Preview", func() {
+	Describe("#ReadImages", func() {
+		It("should read one image per line and skip empty lines and comments", func() {
+			Expect(ReadImages(strings.NewReader("nginx\n\n  # comment\n registry.k8s.io/pause:3.10 \n"))).To(Equal([]string{"nginx", "registry.k8s.io/pause:3.10"}))
+		})
+	})
+
+	Describe("#WritePreview", func() {
+		var config *v1alpha1.Configuration
+
+		BeforeEach(func() {
+			config = &v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{
+						Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+						Targets: []v1alpha1.TargetConfiguration{
+							{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example.com/k8s")}, Provider: "aws", Regions: []string{"eu-west-1"}},
+						},
+					},
+				},
+				Containerd: []v1alpha1.ContainerdConfiguration{
+					{
+						Upstream: "docker.io",
+						Server:   "https://registry-1.docker.io",
+						Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://mirror.example.com", Provider: "aws"}},
+					},
+				},
+			}
+		})
+
+		It("should print the rewrites and the hosts.toml files", func() {
+			out := &bytes.Buffer{}
+			Expect(WritePreview(out, config, "aws", "eu-west-1", []string{"registry.k8s.io/pause:3.10", "nginx"})).To(Succeed())
+			Expect(out.String()).To(Equal(`registry.k8s.io/pause:3.10 → mirror.example.com/k8s/pause:3.10
+nginx (unchanged)
+
+# /etc/containerd/certs.d/docker.io/hosts.toml
+server = "https://registry-1.docker.io"
+
+[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+`))
+		})
+
+		It("should name the matching rule if it has no target for the provider and region", func() {
+			out := &bytes.Buffer{}
+			Expect(WritePreview(out, config, "gcp", "europe-west1", []string{"registry.k8s.io/pause:3.10"})).To(Succeed())
+			Expect(out.String()).To(Equal(`registry.k8s.io/pause:3.10 (unchanged, registry.k8s.io has no target for provider "gcp" and region "europe-west1")
+`))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/validation"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+)
+
+// ExtensionType is the type of the image rewriter Extension resource. Gardener names the Extension resource in the
+// shoot namespace after its type.
+const ExtensionType = "image-rewriter"
+
+var decoder runtime.Decoder
+
+func init() {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(imagerewriterv1alpha1.AddToScheme(scheme))
+
+	decoder = serializer.NewCodecFactory(scheme, serializer.EnableStrict).UniversalDecoder()
+}
+
+// DecodeProviderConfig decodes and validates the shoot specific configuration passed via the provider config of the
+// Extension resource. It returns nil if no provider config is set.
+func DecodeProviderConfig(providerConfig *runtime.RawExtension) (*imagerewriterv1alpha1.ImageRewriterConfig, error) {
+	if providerConfig == nil || len(providerConfig.Raw) == 0 {
+		return nil, nil
+	}
+
+	shootConfig := &imagerewriterv1alpha1.ImageRewriterConfig{}
+	if err := runtime.DecodeInto(decoder, providerConfig.Raw, shootConfig); err != nil {
+		return nil, fmt.Errorf("failed to decode provider config: %w", err)
+	}
+
+	if errs := validation.ValidateImageRewriterConfig(shootConfig, field.NewPath("providerConfig")); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid provider config: %w", errs.ToAggregate())
+	}
+
+	return shootConfig, nil
+}
+
+// Merge merges the shoot specific configuration on top of the global configuration. Overwrites of the shoot come before
+// the global overwrites, i.e. they take precedence in the 'FirstMatch' mode but only over global overwrites of the same
+// precedence in the 'LongestPrefix' mode, see image.MatchOrder. Containerd upstreams of the shoot replace global
+// upstreams with the same name. The passed global configuration is not modified.
+func Merge(global *v1alpha1.Configuration, shootConfig *imagerewriterv1alpha1.ImageRewriterConfig) *v1alpha1.Configuration {
+	if shootConfig == nil {
+		return global
+	}
+
+	merged := &v1alpha1.Configuration{
+		TypeMeta:   global.TypeMeta,
+		Matching:   global.Matching,
+		PodWebhook: global.PodWebhook.DeepCopy(),
+	}
+
+	for _, overwrite := range shootConfig.Overwrites {
+		merged.Overwrites = append(merged.Overwrites, *overwrite.DeepCopy())
+	}
+	for _, overwrite := range global.Overwrites {
+		merged.Overwrites = append(merged.Overwrites, *overwrite.DeepCopy())
+	}
+
+	shootUpstreams := make(map[string]struct{}, len(shootConfig.Containerd))
+	for _, containerdConfig := range shootConfig.Containerd {
+		shootUpstreams[containerdConfig.Upstream] = struct{}{}
+	}
+	for _, containerdConfig := range global.Containerd {
+		if _, exists := shootUpstreams[containerdConfig.Upstream]; !exists {
+			merged.Containerd = append(merged.Containerd, *containerdConfig.DeepCopy())
+		}
+	}
+	for _, containerdConfig := range shootConfig.Containerd {
+		merged.Containerd = append(merged.Containerd, *containerdConfig.DeepCopy())
+	}
+
+	return merged
+}
+
+// MergeFragments merges the given configuration fragments in order. Their overwrites and containerd upstreams are
+// concatenated, the matching mode and the pod webhook configuration are taken from the first fragment which sets them.
+// Conflicts between the fragments must be validated beforehand, see validation.ValidateFragments.
+func MergeFragments(fragments ...*v1alpha1.Configuration) *v1alpha1.Configuration {
+	merged := &v1alpha1.Configuration{}
+
+	for _, fragment := range fragments {
+		if merged.APIVersion == "" {
+			merged.TypeMeta = fragment.TypeMeta
+		}
+		if merged.Matching == "" {
+			merged.Matching = fragment.Matching
+		}
+		if merged.PodWebhook == nil {
+			merged.PodWebhook = fragment.PodWebhook.DeepCopy()
+		}
+
+		for _, overwrite := range fragment.Overwrites {
+			merged.Overwrites = append(merged.Overwrites, *overwrite.DeepCopy())
+		}
+		for _, containerdConfig := range fragment.Containerd {
+			merged.Containerd = append(merged.Containerd, *containerdConfig.DeepCopy())
+		}
+	}
+
+	return merged
+}
+
+// ForExtension returns the configuration which applies to the shoot of the given Extension resource.
+func ForExtension(global *v1alpha1.Configuration, ext *extensionsv1alpha1.Extension) (*v1alpha1.Configuration, error) {
+	shootConfig, err := DecodeProviderConfig(ext.Spec.ProviderConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return Merge(global, shootConfig), nil
+}
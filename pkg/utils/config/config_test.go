@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	imagerewriterv1alpha1 "github.com/gardener/gardener-extension-image-rewriter/pkg/apis/imagerewriter/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		global      *v1alpha1.Configuration
+		shootConfig *imagerewriterv1alpha1.ImageRewriterConfig
+
+		rawShootConfig []byte
+	)
+
+	BeforeEach(func() {
+		global = &v1alpha1.Configuration{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Prefix: ptr.To("global.mirror/k8s")}, Provider: "local"},
+					},
+				},
+			},
+			Containerd: []v1alpha1.ContainerdConfiguration{
+				{
+					Upstream: "registry.k8s.io",
+					Server:   "https://registry.k8s.io",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://global.mirror", Provider: "local"}},
+				},
+				{
+					Upstream: "docker.io",
+					Server:   "https://registry-1.docker.io",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://global.mirror/docker", Provider: "local"}},
+				},
+			},
+		}
+
+		shootConfig = &imagerewriterv1alpha1.ImageRewriterConfig{
+			Overwrites: []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io/pause")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Prefix: ptr.To("shoot.mirror/pause")}, Provider: "local"},
+					},
+				},
+			},
+			Containerd: []v1alpha1.ContainerdConfiguration{
+				{
+					Upstream: "registry.k8s.io",
+					Server:   "https://registry.k8s.io",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://shoot.mirror", Provider: "local"}},
+				},
+			},
+		}
+
+		rawShootConfig = []byte(`{
+  "apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1",
+  "kind": "ImageRewriterConfig",
+  "overwrites": [{
+    "source": {"prefix": "registry.k8s.io/pause"},
+    "targets": [{"prefix": "shoot.mirror/pause", "provider": "local"}]
+  }],
+  "containerd": [{
+    "upstream": "registry.k8s.io",
+    "server": "https://registry.k8s.io",
+    "hosts": [{"url": "https://shoot.mirror", "provider": "local"}]
+  }]
+}`)
+	})
+
+	Describe("#DecodeProviderConfig", func() {
+		It("should return nil if no provider config is set", func() {
+			Expect(DecodeProviderConfig(nil)).To(BeNil())
+		})
+
+		It("should decode a valid provider config", func() {
+			Expect(DecodeProviderConfig(&runtime.RawExtension{Raw: rawShootConfig})).To(Equal(&imagerewriterv1alpha1.ImageRewriterConfig{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "image-rewriter.extensions.gardener.cloud/v1alpha1",
+					Kind:       "ImageRewriterConfig",
+				},
+				Overwrites: shootConfig.Overwrites,
+				Containerd: shootConfig.Containerd,
+			}))
+		})
+
+		It("should fail for an invalid provider config", func() {
+			_, err := DecodeProviderConfig(&runtime.RawExtension{Raw: []byte(`{
+  "apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1",
+  "kind": "ImageRewriterConfig",
+  "overwrites": [{"source": {"prefix": "registry.k8s.io"}}]
+}`)})
+			Expect(err).To(MatchError(ContainSubstring("providerConfig.overwrites[0].targets")))
+		})
+	})
+
+	Describe("#Merge", func() {
+		It("should return the global configuration if no shoot configuration is given", func() {
+			Expect(Merge(global, nil)).To(BeIdenticalTo(global))
+		})
+
+		It("should merge the shoot configuration on top of the global configuration", func() {
+			merged := Merge(global, shootConfig)
+
+			Expect(merged.Overwrites).To(Equal([]v1alpha1.ImageOverwrite{shootConfig.Overwrites[0], global.Overwrites[0]}))
+			Expect(merged.Containerd).To(Equal([]v1alpha1.ContainerdConfiguration{global.Containerd[1], shootConfig.Containerd[0]}))
+		})
+
+		It("should keep the pod webhook configuration of the global configuration", func() {
+			global.PodWebhook = &v1alpha1.PodWebhookConfiguration{
+				ObjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "platform"}},
+			}
+
+			Expect(Merge(global, shootConfig).PodWebhook).To(Equal(global.PodWebhook))
+		})
+
+		It("should keep the matching mode of the global configuration", func() {
+			global.Matching = v1alpha1.MatchingModeLongestPrefix
+
+			Expect(Merge(global, shootConfig).Matching).To(Equal(v1alpha1.MatchingModeLongestPrefix))
+		})
+	})
+
+	Describe("#MergeFragments", func() {
+		It("should concatenate the overwrites and containerd upstreams of the fragments in order", func() {
+			fragment := &v1alpha1.Configuration{
+				Overwrites: shootConfig.Overwrites,
+				Containerd: shootConfig.Containerd,
+				PodWebhook: &v1alpha1.PodWebhookConfiguration{WorkloadKinds: []string{"Deployment"}},
+			}
+
+			global.Matching = ""
+			fragment.Matching = v1alpha1.MatchingModeLongestPrefix
+
+			merged := MergeFragments(global, fragment)
+			Expect(merged.Matching).To(Equal(v1alpha1.MatchingModeLongestPrefix))
+			Expect(merged.Overwrites).To(Equal([]v1alpha1.ImageOverwrite{global.Overwrites[0], shootConfig.Overwrites[0]}))
+			Expect(merged.Containerd).To(Equal([]v1alpha1.ContainerdConfiguration{global.Containerd[0], global.Containerd[1], shootConfig.Containerd[0]}))
+			Expect(merged.PodWebhook).To(Equal(fragment.PodWebhook))
+		})
+
+		It("should return an empty configuration if no fragment is given", func() {
+			Expect(MergeFragments()).To(Equal(&v1alpha1.Configuration{}))
+		})
+	})
+})
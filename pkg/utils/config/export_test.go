@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"k8s.io/utils/lru"
+)
+
+// SetNamespaceCacheSize replaces the namespace cache of the store with an empty cache of the given size.
+func SetNamespaceCacheSize(s *Store, size int) {
+	s.namespaces = lru.New(size)
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/lru"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+// Store holds the global configuration of the extension. The webhooks and the controller read the configuration from
+// the store for every request, hence it can be replaced at runtime, e.g. when the configuration file changes.
+type Store struct {
+	config atomic.Pointer[v1alpha1.Configuration]
+	// global caches the compiled image configuration of the global configuration, see compiledGlobal.
+	global atomic.Pointer[compiledConfig]
+
+	lock    sync.Mutex
+	changed chan struct{}
+
+	// namespaces caches the configurations of the shoot namespaces, see ForNamespace. The cache is bounded, hence
+	// entries of deleted shoots are evicted eventually.
+	namespaces *lru.Cache
+}
+
+// namespaceCacheSize is the maximum number of shoot namespaces whose configuration is cached.
+const namespaceCacheSize = 1000
+
+// compiledConfig is a configuration together with its compiled image configuration, which is expensive to create.
+type compiledConfig struct {
+	config *v1alpha1.Configuration
+	images image.Configuration
+}
+
+// namespaceConfig is the cached configuration of a shoot namespace. It is valid as long as the global configuration
+// and the generation of the Extension resource are unchanged. If the provider config of the Extension is invalid, err
+// is set and the global configuration is cached instead.
+type namespaceConfig struct {
+	compiledConfig
+	global     *v1alpha1.Configuration
+	uid        types.UID
+	generation int64
+	err        error
+}
+
+// NewStore creates a new Store holding the given configuration.
+func NewStore(config *v1alpha1.Configuration) *Store {
+	s := &Store{namespaces: lru.New(namespaceCacheSize)}
+	s.Set(config)
+	return s
+}
+
+// Get returns the current configuration. The returned configuration must not be modified.
+// It returns an empty configuration if the store is nil or no configuration was set.
+func (s *Store) Get() *v1alpha1.Configuration {
+	if s == nil {
+		return &v1alpha1.Configuration{}
+	}
+	if config := s.config.Load(); config != nil {
+		return config
+	}
+	return &v1alpha1.Configuration{}
+}
+
+// Set atomically replaces the configuration. Requests which are in flight keep using the previous configuration.
+func (s *Store) Set(config *v1alpha1.Configuration) {
+	s.config.Store(config)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+}
+
+// Changed returns a channel which is closed when the configuration is replaced the next time.
+func (s *Store) Changed() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.changed
+}
+
+// ForNamespace returns the configuration which applies to the shoot of the given namespace, see ForExtension. If the
+// namespace does not contain an image rewriter Extension resource, the global configuration is returned.
+// The configuration is cached per namespace until the global configuration or the generation of the Extension changes.
+// An invalid provider config must not block the admission of pods and OperatingSystemConfigs, hence the global
+// configuration is returned instead and the error is logged. The controller reports the error in the Extension status.
+func (s *Store) ForNamespace(ctx context.Context, log logr.Logger, reader client.Reader, namespace string) (*v1alpha1.Configuration, error) {
+	compiled, err := s.forNamespace(ctx, log, reader, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.config, nil
+}
+
+// ImagesForNamespace returns the compiled image configuration of the configuration returned by ForNamespace. It is
+// cached together with the configuration.
+func (s *Store) ImagesForNamespace(ctx context.Context, log logr.Logger, reader client.Reader, namespace string) (image.Configuration, error) {
+	compiled, err := s.forNamespace(ctx, log, reader, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.images, nil
+}
+
+// ForExtension returns the configuration which applies to the shoot of the given Extension resource and its compiled
+// image configuration, see the ForExtension function. Unlike ForNamespace, it returns the error of an invalid provider
+// config. The result is cached like the one of ForNamespace.
+func (s *Store) ForExtension(ext *extensionsv1alpha1.Extension) (*v1alpha1.Configuration, image.Configuration, error) {
+	cached, _ := s.forExtension(ext)
+	if cached.err != nil {
+		return nil, nil, cached.err
+	}
+	return cached.config, cached.images, nil
+}
+
+func (s *Store) forNamespace(ctx context.Context, log logr.Logger, reader client.Reader, namespace string) (*compiledConfig, error) {
+	ext := &extensionsv1alpha1.Extension{}
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ExtensionType}, ext); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to get extension: %w", err)
+		}
+		if s != nil && s.namespaces != nil {
+			s.namespaces.Remove(namespace)
+		}
+		return s.compiledGlobal(), nil
+	}
+
+	cached, hit := s.forExtension(ext)
+	if cached.err != nil && !hit {
+		log.Error(cached.err, "Invalid provider config of Extension, falling back to the global configuration", "extension", client.ObjectKeyFromObject(ext), "generation", ext.Generation)
+	}
+	return &cached.compiledConfig, nil
+}
+
+// forExtension returns the cached configuration of the given Extension resource or creates and caches it. It returns
+// true if the configuration was cached.
+func (s *Store) forExtension(ext *extensionsv1alpha1.Extension) (*namespaceConfig, bool) {
+	global := s.compiledGlobal()
+
+	if s != nil && s.namespaces != nil {
+		if value, ok := s.namespaces.Get(ext.Namespace); ok {
+			if cached := value.(*namespaceConfig); cached.global == global.config && cached.uid == ext.UID && cached.generation == ext.Generation {
+				return cached, true
+			}
+		}
+	}
+
+	entry := &namespaceConfig{
+		compiledConfig: *global,
+		global:         global.config,
+		uid:            ext.UID,
+		generation:     ext.Generation,
+	}
+	config, err := ForExtension(global.config, ext)
+	switch {
+	case err != nil:
+		entry.err = err
+	case config != global.config:
+		entry.compiledConfig = compiledConfig{config: config, images: image.NewImageConfiguration(config)}
+	}
+
+	if s != nil && s.namespaces != nil {
+		s.namespaces.Add(ext.Namespace, entry)
+	}
+	return entry, false
+}
+
+// compiledGlobal returns the global configuration with its compiled image configuration. The image configuration is
+// compiled once per global configuration.
+func (s *Store) compiledGlobal() *compiledConfig {
+	global := s.Get()
+	if s != nil {
+		if cached := s.global.Load(); cached != nil && cached.config == global {
+			return cached
+		}
+	}
+
+	compiled := &compiledConfig{config: global, images: image.NewImageConfiguration(global)}
+	if s != nil {
+		s.global.Store(compiled)
+	}
+	return compiled
+}
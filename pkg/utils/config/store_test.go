@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config_test
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+var _ = Describe("Store", func() {
+	It("should return the configuration it was created with", func() {
+		config := &v1alpha1.Configuration{Overwrites: []v1alpha1.ImageOverwrite{{Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com")}}}}
+		Expect(NewStore(config).Get()).To(BeIdenticalTo(config))
+	})
+
+	It("should return the replaced configuration", func() {
+		store := NewStore(&v1alpha1.Configuration{})
+		config := &v1alpha1.Configuration{Overwrites: []v1alpha1.ImageOverwrite{{Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com")}}}}
+
+		store.Set(config)
+		Expect(store.Get()).To(BeIdenticalTo(config))
+	})
+
+	It("should return an empty configuration if none is set", func() {
+		var store *Store
+		Expect(store.Get()).To(Equal(&v1alpha1.Configuration{}))
+		Expect((&Store{}).Get()).To(Equal(&v1alpha1.Configuration{}))
+	})
+
+	It("should notify about replaced configurations", func() {
+		store := NewStore(&v1alpha1.Configuration{})
+
+		changed := store.Changed()
+		Expect(store.Changed()).To(Equal(changed))
+		Consistently(changed).ShouldNot(BeClosed())
+
+		store.Set(&v1alpha1.Configuration{})
+		Expect(changed).To(BeClosed())
+		Expect(store.Changed()).NotTo(BeClosed())
+	})
+
+	Describe("#ForNamespace", func() {
+		var (
+			ctx        context.Context
+			fakeClient client.Client
+			namespace  string
+
+			global *v1alpha1.Configuration
+			store  *Store
+			ext    *extensionsv1alpha1.Extension
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			scheme := runtime.NewScheme()
+			Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+			fakeClient = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+
+			namespace = "shoot--test--local"
+
+			global = &v1alpha1.Configuration{
+				Overwrites: []v1alpha1.ImageOverwrite{{
+					Source:  v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("global.mirror/k8s")}, Provider: "local"}},
+				}},
+			}
+			store = NewStore(global)
+
+			ext = &extensionsv1alpha1.Extension{
+				ObjectMeta: metav1.ObjectMeta{Name: ExtensionType, Namespace: namespace, Generation: 1},
+				Spec: extensionsv1alpha1.ExtensionSpec{
+					DefaultSpec: extensionsv1alpha1.DefaultSpec{
+						Type: ExtensionType,
+						ProviderConfig: &runtime.RawExtension{Raw: []byte(`{
+  "apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1",
+  "kind": "ImageRewriterConfig",
+  "overwrites": [{
+    "source": {"prefix": "registry.k8s.io/pause"},
+    "targets": [{"prefix": "shoot.mirror/pause", "provider": "local"}]
+  }]
+}`)},
+					},
+				},
+			}
+		})
+
+		It("should return the global configuration if the extension does not exist", func() {
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(global))
+		})
+
+		It("should return the merged configuration of the extension", func() {
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+
+			merged, err := store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(merged.Overwrites).To(HaveLen(2))
+			Expect(merged.Overwrites[0].Source.Prefix).To(Equal(ptr.To("registry.k8s.io/pause")))
+		})
+
+		It("should cache the merged configuration until the generation of the extension changes", func() {
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+
+			merged, err := store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(merged))
+
+			ext.Generation = 2
+			Expect(fakeClient.Update(ctx, ext)).To(Succeed())
+
+			updated, err := store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated).To(Equal(merged))
+			Expect(updated).NotTo(BeIdenticalTo(merged))
+		})
+
+		It("should not use the cached configuration if the global configuration changed", func() {
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(HaveField("Overwrites", HaveLen(2)))
+
+			store.Set(&v1alpha1.Configuration{})
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(HaveField("Overwrites", HaveLen(1)))
+		})
+
+		It("should evict the least recently used namespace if the cache is full", func() {
+			SetNamespaceCacheSize(store, 1)
+			otherExt := ext.DeepCopy()
+			otherExt.Namespace = "shoot--test--other"
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+			Expect(fakeClient.Create(ctx, otherExt)).To(Succeed())
+
+			merged, err := store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(merged))
+
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, otherExt.Namespace)).To(HaveField("Overwrites", HaveLen(2)))
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).NotTo(BeIdenticalTo(merged))
+		})
+
+		It("should return the global configuration if the provider config is invalid", func() {
+			ext.Spec.ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1", "kind": "ImageRewriterConfig", "unknown": true}`)}
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+
+			Expect(store.ForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(global))
+		})
+
+		It("should cache the compiled image configuration together with the configuration", func() {
+			Expect(fakeClient.Create(ctx, ext)).To(Succeed())
+
+			images, err := store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images.FindTargetImage("registry.k8s.io/pause:3.10", "local", "west")).To(Equal("shoot.mirror/pause:3.10"))
+			Expect(store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(images))
+
+			ext.Generation = 2
+			Expect(fakeClient.Update(ctx, ext)).To(Succeed())
+			Expect(store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)).NotTo(BeIdenticalTo(images))
+		})
+
+		It("should compile the image configuration of the global configuration once", func() {
+			images, err := store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images.FindTargetImage("registry.k8s.io/pause:3.10", "local", "west")).To(Equal("global.mirror/k8s/pause:3.10"))
+			Expect(store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)).To(BeIdenticalTo(images))
+
+			store.Set(&v1alpha1.Configuration{})
+			Expect(store.ImagesForNamespace(ctx, logr.Discard(), fakeClient, namespace)).NotTo(BeIdenticalTo(images))
+		})
+	})
+
+	Describe("#ForExtension", func() {
+		var (
+			store *Store
+			ext   *extensionsv1alpha1.Extension
+		)
+
+		BeforeEach(func() {
+			store = NewStore(&v1alpha1.Configuration{})
+			ext = &extensionsv1alpha1.Extension{
+				ObjectMeta: metav1.ObjectMeta{Name: ExtensionType, Namespace: "shoot--test--local", Generation: 1},
+			}
+		})
+
+		It("should return the configuration and the compiled image configuration", func() {
+			config, images, err := store.ForExtension(ext)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(BeIdenticalTo(store.Get()))
+			Expect(images.HasOverwrite("local", "west")).To(BeFalse())
+		})
+
+		It("should return the error of an invalid provider config", func() {
+			ext.Spec.ProviderConfig = &runtime.RawExtension{Raw: []byte(`{"apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1", "kind": "ImageRewriterConfig", "unknown": true}`)}
+
+			_, _, err := store.ForExtension(ext)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode provider config")))
+			_, _, err = store.ForExtension(ext)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -10,14 +10,46 @@ import (
 	"k8s.io/utils/ptr"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/selector"
 )
 
 // UpStreamConfiguration contains the upstream configuration for containerd.
 type UpStreamConfiguration struct {
-	Upstream     string
-	Server       string
-	HostURL      string
+	Upstream      string
+	Server        string
+	MergeStrategy v1alpha1.MergeStrategy
+	// Hosts are the hosts which containerd tries in the given order before it falls back to the server.
+	Hosts []Host
+}
+
+// Host contains the configuration of a containerd host.
+type Host struct {
+	URL string
+	// Capabilities are the configured capabilities of the host, see EffectiveCapabilities.
+	Capabilities []v1alpha1.ContainerdCapability
+	Header       map[string][]string
 	OverridePath *bool
+	// TLS contains the TLS settings of the host, if any.
+	TLS *TLS
+}
+
+// EffectiveCapabilities returns the capabilities of the host, hosts without configured capabilities have the default
+// capabilities.
+func (h Host) EffectiveCapabilities() []v1alpha1.ContainerdCapability {
+	if len(h.Capabilities) == 0 {
+		return defaultCapabilities
+	}
+	return h.Capabilities
+}
+
+// TLS contains the TLS settings of a containerd host.
+type TLS struct {
+	// CA is the PEM encoded CA bundle which is used to verify the certificate of the host.
+	CA string
+	// ClientCert and ClientKey are the PEM encoded client certificate and key which are presented to the host.
+	ClientCert string
+	ClientKey  string
+	SkipVerify bool
 }
 
 // Configuration defines the interface for operating on image configurations.
@@ -31,44 +63,50 @@ type configuration struct {
 }
 
 type upstreamConfig struct {
-	upstream        string
-	server          string
-	providerToHosts map[string]hostConfig
+	upstream      string
+	server        string
+	mergeStrategy v1alpha1.MergeStrategy
+	hosts         selector.Selector[v1alpha1.ContainerdHostConfig]
 }
 
-type hostConfig struct {
-	regionToURL map[string]string
-	globalURL   string
-}
-
-var hostWithPathPattern = regexp.MustCompile(`https?://[a-zA-Z0-9\.\-]+(/[^\s]*)+`)
+var (
+	hostWithPathPattern = regexp.MustCompile(`https?://[a-zA-Z0-9\.\-]+(/[^\s]*)+`)
+	// defaultCapabilities are the capabilities of hosts which don't configure them.
+	defaultCapabilities = []v1alpha1.ContainerdCapability{v1alpha1.ContainerdCapabilityPull, v1alpha1.ContainerdCapabilityResolve}
+)
 
 // GetUpstreamConfig returns the containerd upstream configuration based on provider and region.
 func (c *configuration) GetUpstreamConfig(provider string, region string) []UpStreamConfiguration {
 	result := make([]UpStreamConfiguration, 0, len(c.upstreamConfigs))
 
 	for _, upstreamConf := range c.upstreamConfigs {
-		if hosts, providerExists := upstreamConf.providerToHosts[provider]; providerExists {
-			hostURL := hosts.regionToURL[region]
-			if hostURL == "" {
-				hostURL = hosts.globalURL
-			}
+		hostConfig, ok := upstreamConf.hosts.Select(provider, region)
+		if !ok {
+			continue
+		}
 
-			if hostURL != "" {
-				// If the host URL contains a path, override_path needs to be set to true, see https://github.com/containerd/containerd/blob/main/docs/hosts.md#override_path-field.
-				var overridePath *bool
-				if hostWithPathPattern.MatchString(hostURL) {
-					overridePath = ptr.To(true)
-				}
-
-				result = append(result, UpStreamConfiguration{
-					Upstream:     upstreamConf.upstream,
-					Server:       upstreamConf.server,
-					HostURL:      hostURL,
-					OverridePath: overridePath,
-				})
+		upstream := UpStreamConfiguration{
+			Upstream:      upstreamConf.upstream,
+			Server:        upstreamConf.server,
+			MergeStrategy: upstreamConf.mergeStrategy,
+		}
+		tls := hostTLS(hostConfig)
+		for _, hostURL := range hostURLs(hostConfig) {
+			// If the host URL contains a path, override_path needs to be set to true, see https://github.com/containerd/containerd/blob/main/docs/hosts.md#override_path-field.
+			var overridePath *bool
+			if hostWithPathPattern.MatchString(hostURL) {
+				overridePath = ptr.To(true)
 			}
+
+			upstream.Hosts = append(upstream.Hosts, Host{
+				URL:          hostURL,
+				Capabilities: hostConfig.Capabilities,
+				Header:       hostConfig.Header,
+				OverridePath: overridePath,
+				TLS:          tls,
+			})
 		}
+		result = append(result, upstream)
 	}
 
 	return result
@@ -89,29 +127,40 @@ func NewConfiguration(config *v1alpha1.Configuration) Configuration {
 
 func createUpstreamConfig(containerdUpstreamConfig v1alpha1.ContainerdConfiguration) upstreamConfig {
 	upstream := upstreamConfig{
-		upstream: containerdUpstreamConfig.Upstream,
-		server:   containerdUpstreamConfig.Server,
+		upstream:      containerdUpstreamConfig.Upstream,
+		server:        containerdUpstreamConfig.Server,
+		mergeStrategy: containerdUpstreamConfig.MergeStrategy,
 	}
 
 	for _, host := range containerdUpstreamConfig.Hosts {
-		if upstream.providerToHosts == nil {
-			upstream.providerToHosts = make(map[string]hostConfig)
-		}
+		// A host for any region doesn't replace the hosts for specific regions of the provider.
+		upstream.hosts.Add(host.Provider, host.Regions, host)
+	}
 
-		for _, region := range host.Regions {
-			if upstream.providerToHosts[host.Provider].regionToURL == nil {
-				upstream.providerToHosts[host.Provider] = hostConfig{
-					regionToURL: make(map[string]string),
-				}
-			}
+	return upstream
+}
 
-			upstream.providerToHosts[host.Provider].regionToURL[region] = host.URL
-		}
+// hostTLS returns the TLS settings of the host configuration or nil if it has none.
+func hostTLS(host v1alpha1.ContainerdHostConfig) *TLS {
+	if host.CA == "" && host.Client == nil && !ptr.Deref(host.SkipVerify, false) {
+		return nil
+	}
 
-		if len(host.Regions) == 0 {
-			upstream.providerToHosts[host.Provider] = hostConfig{globalURL: host.URL}
-		}
+	tls := &TLS{
+		CA:         host.CA,
+		SkipVerify: ptr.Deref(host.SkipVerify, false),
+	}
+	if host.Client != nil {
+		tls.ClientCert = host.Client.Cert
+		tls.ClientKey = host.Client.Key
 	}
+	return tls
+}
 
-	return upstream
+// hostURLs returns the URLs of the host configuration in the order they are tried.
+func hostURLs(host v1alpha1.ContainerdHostConfig) []string {
+	if len(host.URLs) > 0 {
+		return host.URLs
+	}
+	return []string{host.URL}
 }
@@ -68,43 +68,42 @@ var _ = Describe("Containerd", func() {
 				for i, expected := range upstreamConfigs {
 					Expect(result[i].Upstream).To(Equal(expected.Upstream), "upstream should match")
 					Expect(result[i].Server).To(Equal(expected.Server), "server should match")
-					Expect(result[i].HostURL).To(Equal(expected.HostURL), "host URL should match")
-					Expect(result[i].OverridePath).To(Equal(expected.OverridePath), "override path should match")
+					Expect(result[i].Hosts).To(Equal(expected.Hosts), "hosts should match")
 				}
 			}
 
 			It("should return the correct upstream configuration for provision", func() {
 				test("local", "west", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-west"},
-					{Upstream: "upstream2", Server: "https://server2", HostURL: "https://mirror2-west"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-west"}}},
+					{Upstream: "upstream2", Server: "https://server2", Hosts: []Host{{URL: "https://mirror2-west"}}},
 				})
 
 				test("local", "central", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-central"},
-					{Upstream: "upstream2", Server: "https://server2", HostURL: "https://mirror2-central"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-central"}}},
+					{Upstream: "upstream2", Server: "https://server2", Hosts: []Host{{URL: "https://mirror2-central"}}},
 				})
 
 				test("local", "south", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-central"},
-					{Upstream: "upstream2", Server: "https://server2", HostURL: "https://mirror2-central"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-central"}}},
+					{Upstream: "upstream2", Server: "https://server2", Hosts: []Host{{URL: "https://mirror2-central"}}},
 				})
 
 				test("local", "north", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-central"},
-					{Upstream: "upstream2", Server: "https://server2", HostURL: "https://mirror2-central"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-central"}}},
+					{Upstream: "upstream2", Server: "https://server2", Hosts: []Host{{URL: "https://mirror2-central"}}},
 				})
 
 				test("local", "east", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-east"},
-					{Upstream: "upstream2", Server: "https://server2", HostURL: "https://mirror2-east"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-east"}}},
+					{Upstream: "upstream2", Server: "https://server2", Hosts: []Host{{URL: "https://mirror2-east"}}},
 				})
 
 				test("local2", "east", []UpStreamConfiguration{
-					{Upstream: "upstream3", Server: "https://server3", HostURL: "https://mirror3/east", OverridePath: ptr.To(true)},
+					{Upstream: "upstream3", Server: "https://server3", Hosts: []Host{{URL: "https://mirror3/east", OverridePath: ptr.To(true)}}},
 				})
 
 				test("global", "any-region", []UpStreamConfiguration{
-					{Upstream: "upstream1", Server: "https://server1", HostURL: "https://mirror1-global"},
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-global"}}},
 				})
 			})
 
@@ -123,6 +122,112 @@ var _ = Describe("Containerd", func() {
 
 				test("local3", "west", []UpStreamConfiguration{})
 			})
+
+			It("should keep the hosts for specific regions if a host for any region of the provider follows", func() {
+				containerdConfig = NewConfiguration(&v1alpha1.Configuration{
+					Containerd: []v1alpha1.ContainerdConfiguration{
+						{
+							Upstream: "upstream1",
+							Server:   "https://server1",
+							Hosts: []v1alpha1.ContainerdHostConfig{
+								{URL: "https://mirror1-west", Provider: "local", Regions: []string{"west"}},
+								{URL: "https://mirror1-global", Provider: "local"},
+							},
+						},
+					},
+				})
+
+				test("local", "west", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-west"}}},
+				})
+
+				test("local", "east", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-global"}}},
+				})
+			})
+
+			It("should return the hosts in the configured order", func() {
+				containerdConfig = NewConfiguration(&v1alpha1.Configuration{
+					Containerd: []v1alpha1.ContainerdConfiguration{
+						{
+							Upstream: "upstream1",
+							Server:   "https://server1",
+							Hosts: []v1alpha1.ContainerdHostConfig{
+								{URLs: []string{"https://mirror1-west", "https://mirror1/central"}, Provider: "local", Regions: []string{"west"}},
+							},
+						},
+					},
+				})
+
+				test("local", "west", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{
+						{URL: "https://mirror1-west"},
+						{URL: "https://mirror1/central", OverridePath: ptr.To(true)},
+					}},
+				})
+			})
+
+			It("should return the TLS settings of the hosts", func() {
+				containerdConfig = NewConfiguration(&v1alpha1.Configuration{
+					Containerd: []v1alpha1.ContainerdConfiguration{
+						{
+							Upstream: "upstream1",
+							Server:   "https://server1",
+							Hosts: []v1alpha1.ContainerdHostConfig{
+								{
+									URLs:       []string{"https://mirror1-west", "https://mirror1-east"},
+									Provider:   "local",
+									CA:         "ca",
+									Client:     &v1alpha1.ContainerdClientCertificate{Cert: "cert", Key: "key"},
+									SkipVerify: ptr.To(true),
+								},
+								{URL: "https://mirror1-global", Provider: "local2", SkipVerify: ptr.To(false)},
+							},
+						},
+					},
+				})
+
+				tls := &TLS{CA: "ca", ClientCert: "cert", ClientKey: "key", SkipVerify: true}
+				test("local", "west", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{
+						{URL: "https://mirror1-west", TLS: tls},
+						{URL: "https://mirror1-east", TLS: tls},
+					}},
+				})
+				test("local2", "west", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-global"}}},
+				})
+			})
+
+			It("should prefer the exact region over a region pattern over any region", func() {
+				containerdConfig = NewConfiguration(&v1alpha1.Configuration{
+					Containerd: []v1alpha1.ContainerdConfiguration{
+						{
+							Upstream: "upstream1",
+							Server:   "https://server1",
+							Hosts: []v1alpha1.ContainerdHostConfig{
+								{URL: "https://mirror1-global", Provider: "local"},
+								{URL: "https://mirror1-europe", Provider: "local", Regions: []string{"eu-*"}},
+								{URL: "https://mirror1-ireland", Provider: "local", Regions: []string{"eu-west-1"}},
+								{URL: "https://mirror1-any", Provider: "*"},
+							},
+						},
+					},
+				})
+
+				test("local", "eu-west-1", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-ireland"}}},
+				})
+				test("local", "eu-central-1", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-europe"}}},
+				})
+				test("local", "us-east-1", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-global"}}},
+				})
+				test("local2", "us-east-1", []UpStreamConfiguration{
+					{Upstream: "upstream1", Server: "https://server1", Hosts: []Host{{URL: "https://mirror1-any"}}},
+				})
+			})
 		})
 	})
 })
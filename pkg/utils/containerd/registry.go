@@ -6,10 +6,18 @@ package containerd
 
 import (
 	"bytes"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"text/template"
 )
 
+// CertsDir is the directory containing the containerd registry host configurations.
+const CertsDir = "/etc/containerd/certs.d"
+
 var (
 	//go:embed templates/hosts.toml.tpl
 	tplContentHosts string
@@ -19,25 +27,83 @@ var (
 func init() {
 	tplHosts = template.Must(template.
 		New("hosts.toml").
+		Funcs(template.FuncMap{"toml": tomlString}).
 		Parse(tplContentHosts))
 }
 
+// tomlString returns the given value as a TOML basic string. Unlike strconv.Quote, it only emits escape sequences which
+// are valid in TOML, see https://toml.io/en/v1.0.0#string.
+func tomlString(value any) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+	for _, r := range fmt.Sprint(value) {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
 // RegistryMirror represents a registry mirror for containerd.
 type RegistryMirror struct {
+	Upstream       string
 	UpstreamServer string
-	MirrorHost     string
-	OverridePath   *bool
+	// MirrorHosts are tried in the given order.
+	MirrorHosts []Host
+}
+
+// CertificateFile is a file with PEM material which is referenced by the hosts.toml file of an upstream.
+type CertificateFile struct {
+	Path        string
+	Content     string
+	Permissions uint32
 }
 
 // HostsTOML returns hosts.toml configuration.
 func (r *RegistryMirror) HostsTOML() (string, error) {
-	values := map[string]any{
-		"server": r.UpstreamServer,
-		"host":   r.MirrorHost,
+	hosts := make([]map[string]any, 0, len(r.MirrorHosts))
+	for _, mirrorHost := range r.MirrorHosts {
+		host := map[string]any{
+			"url":          mirrorHost.URL,
+			"capabilities": mirrorHost.EffectiveCapabilities(),
+			"header":       mirrorHost.Header,
+		}
+		if mirrorHost.OverridePath != nil {
+			host["overridePath"] = *mirrorHost.OverridePath
+		}
+		if tls := mirrorHost.TLS; tls != nil {
+			host["ca"] = tls.CAPath(r.Upstream)
+			host["clientCert"], host["clientKey"] = tls.clientPaths(r.Upstream)
+			host["skipVerify"] = tls.SkipVerify
+		}
+		hosts = append(hosts, host)
 	}
 
-	if r.OverridePath != nil {
-		values["overridePath"] = *r.OverridePath
+	values := map[string]any{
+		"server": r.UpstreamServer,
+		"hosts":  hosts,
 	}
 
 	hostsTOML := bytes.NewBuffer(nil)
@@ -49,3 +115,72 @@ func (r *RegistryMirror) HostsTOML() (string, error) {
 
 	return hostsTOML.String(), nil
 }
+
+// Files returns the files with the PEM material which is referenced by the hosts.toml file.
+func (r *RegistryMirror) Files() []CertificateFile {
+	var (
+		files []CertificateFile
+		paths = map[string]bool{}
+	)
+
+	for _, mirrorHost := range r.MirrorHosts {
+		for _, file := range mirrorHost.TLS.Files(r.Upstream) {
+			// Hosts with the same PEM material share the files.
+			if !paths[file.Path] {
+				paths[file.Path] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files
+}
+
+// CAPath returns the path of the CA bundle of the TLS settings for the given upstream. It returns an empty string if
+// there is no CA bundle.
+func (t *TLS) CAPath(upstream string) string {
+	if t == nil || t.CA == "" {
+		return ""
+	}
+	return certificatePath(upstream, "ca", ".crt", t.CA)
+}
+
+func (t *TLS) clientPaths(upstream string) (string, string) {
+	if t == nil || t.ClientCert == "" {
+		return "", ""
+	}
+	return certificatePath(upstream, "client", ".cert", t.ClientCert, t.ClientKey),
+		certificatePath(upstream, "client", ".key", t.ClientCert, t.ClientKey)
+}
+
+// Files returns the files with the PEM material of the TLS settings for the given upstream.
+func (t *TLS) Files(upstream string) []CertificateFile {
+	var files []CertificateFile
+
+	if caPath := t.CAPath(upstream); caPath != "" {
+		files = append(files, CertificateFile{Path: caPath, Content: t.CA, Permissions: 0644})
+	}
+	if certPath, keyPath := t.clientPaths(upstream); certPath != "" {
+		files = append(files,
+			CertificateFile{Path: certPath, Content: t.ClientCert, Permissions: 0644},
+			CertificateFile{Path: keyPath, Content: t.ClientKey, Permissions: 0600},
+		)
+	}
+
+	return files
+}
+
+// certificatePath returns the path of a file with PEM material. The name contains a hash of the material, hence hosts
+// with different material don't share a file and the path changes if the material is rotated.
+func certificatePath(upstream, name, extension string, contents ...string) string {
+	hash := sha256.New()
+	for _, content := range contents {
+		hash.Write([]byte(content))
+	}
+	return filepath.Join(CertsDir, upstream, name+"-"+hex.EncodeToString(hash.Sum(nil))[:16]+extension)
+}
+
+// HostsTOMLPath returns the path of the hosts.toml file for the given upstream.
+func HostsTOMLPath(upstream string) string {
+	return filepath.Join(CertsDir, upstream, "hosts.toml")
+}
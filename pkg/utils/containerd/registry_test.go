@@ -9,6 +9,7 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/utils/ptr"
 
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
 	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/containerd"
 )
 
@@ -17,7 +18,7 @@ var _ = Describe("RegistryMirror", func() {
 		It("generates correct configuration for valid fields", func() {
 			mirror := RegistryMirror{
 				UpstreamServer: "https://upstream.example.com",
-				MirrorHost:     "https://mirror.example.com",
+				MirrorHosts:    []Host{{URL: "https://mirror.example.com"}},
 			}
 			expected := `server = "https://upstream.example.com"
 
@@ -29,9 +30,21 @@ var _ = Describe("RegistryMirror", func() {
 
 		It("handles empty fields gracefully", func() {
 			mirror := RegistryMirror{}
-			expected := `server = ""
+			Expect(mirror.HostsTOML()).To(BeEmpty())
+		})
+
+		It("omits the server for the default upstream", func() {
+			mirror := RegistryMirror{
+				Upstream: "_default",
+				MirrorHosts: []Host{
+					{URL: "https://cache.example.com"},
+					{URL: "https://cache-eu.example.com"},
+				},
+			}
+			expected := `[host."https://cache.example.com"]
+  capabilities = ["pull", "resolve"]
 
-[host.""]
+[host."https://cache-eu.example.com"]
   capabilities = ["pull", "resolve"]
 `
 			Expect(mirror.HostsTOML()).To(Equal(expected))
@@ -40,11 +53,30 @@ var _ = Describe("RegistryMirror", func() {
 		It("handles hosts with path", func() {
 			mirror := RegistryMirror{
 				UpstreamServer: "https://upstream.example.com",
-				MirrorHost:     "https://mirror.example.com/v2/some/path",
-				OverridePath:   ptr.To(true),
+				MirrorHosts:    []Host{{URL: "https://mirror.example.com/v2/some/path", OverridePath: ptr.To(true)}},
+			}
+			expected := `server = "https://upstream.example.com"
+
+[host."https://mirror.example.com/v2/some/path"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+`
+			Expect(mirror.HostsTOML()).To(Equal(expected))
+		})
+
+		It("renders several hosts in the given order", func() {
+			mirror := RegistryMirror{
+				UpstreamServer: "https://upstream.example.com",
+				MirrorHosts: []Host{
+					{URL: "https://mirror-eu.example.com"},
+					{URL: "https://mirror.example.com/v2/some/path", OverridePath: ptr.To(true)},
+				},
 			}
 			expected := `server = "https://upstream.example.com"
 
+[host."https://mirror-eu.example.com"]
+  capabilities = ["pull", "resolve"]
+
 [host."https://mirror.example.com/v2/some/path"]
   capabilities = ["pull", "resolve"]
   override_path = true
@@ -52,4 +84,105 @@ var _ = Describe("RegistryMirror", func() {
 			Expect(mirror.HostsTOML()).To(Equal(expected))
 		})
 	})
+
+	Describe("#HostsTOML with capabilities and header", func() {
+		It("renders the configured capabilities and header of the hosts", func() {
+			mirror := RegistryMirror{
+				Upstream:       "docker.io",
+				UpstreamServer: "https://upstream.example.com",
+				MirrorHosts: []Host{
+					{
+						URL:          "https://mirror.example.com/v2/some/path",
+						Capabilities: []v1alpha1.ContainerdCapability{v1alpha1.ContainerdCapabilityPull},
+						Header:       map[string][]string{"X-Tenant": {"team-a"}, "Accept": {"a", `"b"`}},
+						OverridePath: ptr.To(true),
+					},
+					{URL: "https://mirror-eu.example.com"},
+				},
+			}
+			expected := `server = "https://upstream.example.com"
+
+[host."https://mirror.example.com/v2/some/path"]
+  capabilities = ["pull"]
+  override_path = true
+
+  [host."https://mirror.example.com/v2/some/path".header]
+    "Accept" = ["a", "\"b\""]
+    "X-Tenant" = ["team-a"]
+
+[host."https://mirror-eu.example.com"]
+  capabilities = ["pull", "resolve"]
+`
+			Expect(mirror.HostsTOML()).To(Equal(expected))
+		})
+	})
+
+	Describe("#HostsTOML with special characters", func() {
+		It("escapes all values as TOML strings", func() {
+			mirror := RegistryMirror{
+				Upstream:       "docker.io",
+				UpstreamServer: "https://upstream.example.com\"]\n[host.\"https://evil.example.com\"]",
+				MirrorHosts: []Host{
+					{URL: "https://mirror.example.com\\\x00\x7f\u00e4"},
+				},
+			}
+			expected := `server = "https://upstream.example.com\"]\n[host.\"https://evil.example.com\"]"
+
+[host."https://mirror.example.com\\\u0000\u007Fä"]
+  capabilities = ["pull", "resolve"]
+`
+			Expect(mirror.HostsTOML()).To(Equal(expected))
+		})
+	})
+
+	Describe("#HostsTOML with TLS", func() {
+		It("references the PEM files of the hosts", func() {
+			tls := &TLS{CA: "ca", ClientCert: "cert", ClientKey: "key", SkipVerify: true}
+			mirror := RegistryMirror{
+				Upstream:       "docker.io",
+				UpstreamServer: "https://upstream.example.com",
+				MirrorHosts: []Host{
+					{URL: "https://mirror-eu.example.com", TLS: tls},
+					{URL: "https://mirror.example.com", TLS: &TLS{CA: "other-ca"}},
+				},
+			}
+			expected := `server = "https://upstream.example.com"
+
+[host."https://mirror-eu.example.com"]
+  capabilities = ["pull", "resolve"]
+  ca = "/etc/containerd/certs.d/docker.io/ca-6959097001d10501.crt"
+  client = [["/etc/containerd/certs.d/docker.io/client-44b56c2f52d28dfd.cert", "/etc/containerd/certs.d/docker.io/client-44b56c2f52d28dfd.key"]]
+  skip_verify = true
+
+[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+  ca = "/etc/containerd/certs.d/docker.io/ca-4f6a24591d994ad7.crt"
+`
+			Expect(mirror.HostsTOML()).To(Equal(expected))
+			Expect(mirror.Files()).To(Equal([]CertificateFile{
+				{Path: "/etc/containerd/certs.d/docker.io/ca-6959097001d10501.crt", Content: "ca", Permissions: 0644},
+				{Path: "/etc/containerd/certs.d/docker.io/client-44b56c2f52d28dfd.cert", Content: "cert", Permissions: 0644},
+				{Path: "/etc/containerd/certs.d/docker.io/client-44b56c2f52d28dfd.key", Content: "key", Permissions: 0600},
+				{Path: "/etc/containerd/certs.d/docker.io/ca-4f6a24591d994ad7.crt", Content: "other-ca", Permissions: 0644},
+			}))
+		})
+
+		It("shares the files of hosts with the same PEM material", func() {
+			mirror := RegistryMirror{
+				Upstream: "docker.io",
+				MirrorHosts: []Host{
+					{URL: "https://mirror-eu.example.com", TLS: &TLS{CA: "ca"}},
+					{URL: "https://mirror.example.com", TLS: &TLS{CA: "ca"}},
+					{URL: "https://mirror-us.example.com"},
+				},
+			}
+			Expect(mirror.Files()).To(HaveLen(1))
+		})
+	})
+
+	Describe("#HostsTOMLPath", func() {
+		It("returns the path of the hosts.toml file in the containerd certs directory", func() {
+			Expect(HostsTOMLPath("docker.io")).To(Equal("/etc/containerd/certs.d/docker.io/hosts.toml"))
+		})
+	})
 })
@@ -1,7 +1,27 @@
-server = "{{ .server }}"
-
-[host."{{ .host }}"]
-  capabilities = ["pull", "resolve"]
+{{- if .server }}server = {{ toml .server }}
+{{ end }}
+{{- range $i, $host := .hosts }}
+{{- if or $.server $i }}
+{{ end -}}
+[host.{{ toml .url }}]
+  capabilities = [{{ range $i, $capability := .capabilities }}{{ if $i }}, {{ end }}{{ toml $capability }}{{ end }}]
+{{- if .ca }}
+  ca = {{ toml .ca }}
+{{- end }}
+{{- if .clientCert }}
+  client = [[{{ toml .clientCert }}, {{ toml .clientKey }}]]
+{{- end }}
+{{- if .skipVerify }}
+  skip_verify = true
+{{- end }}
 {{- if .overridePath }}
   override_path = {{ .overridePath }}
 {{- end }}
+{{- with .header }}
+
+  [host.{{ toml $host.url }}.header]
+{{- range $name, $values := . }}
+    {{ toml $name }} = [{{ range $i, $value := $values }}{{ if $i }}, {{ end }}{{ toml $value }}{{ end }}]
+{{- end }}
+{{- end }}
+{{ end -}}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event
+
+import (
+	"context"
+	"fmt"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+)
+
+const (
+	// ReasonImagesRewritten is the reason of events about images which were rewritten.
+	ReasonImagesRewritten = "ImagesRewritten"
+	// ReasonRegistryMirrorsApplied is the reason of events about containerd registry mirrors which were applied.
+	ReasonRegistryMirrorsApplied = "RegistryMirrorsApplied"
+	// ReasonRegistryConfigurationConflict is the reason of events about containerd registry configurations of other
+	// extensions which conflict with the registry mirrors.
+	ReasonRegistryConfigurationConflict = "RegistryConfigurationConflict"
+	// ReasonShootWebhooksRemoved is the reason of events about shoot webhooks which were removed.
+	ReasonShootWebhooksRemoved = "ShootWebhooksRemoved"
+	// ReasonConfigurationChanged is the reason of events about effective configurations of shoots which changed.
+	ReasonConfigurationChanged = "ConfigurationChanged"
+	// ReasonConfigurationReloadFailed is the reason of events about configurations which could not be reloaded.
+	ReasonConfigurationReloadFailed = "ConfigurationReloadFailed"
+
+	// ActionMutate is the action of events which are emitted by webhooks.
+	ActionMutate = "Mutate"
+	// ActionReconcile is the action of events which are emitted by the controller.
+	ActionReconcile = "Reconcile"
+	// ActionReload is the action of events which are emitted when the configuration is reloaded.
+	ActionReload = "Reload"
+)
+
+// ObjectForNamespace returns the object on which events concerning the shoot of the given namespace are recorded.
+// It is the image rewriter Extension resource if it exists, otherwise the Cluster resource.
+func ObjectForNamespace(ctx context.Context, reader client.Reader, namespace string) (client.Object, error) {
+	ext := &extensionsv1alpha1.Extension{}
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: configutils.ExtensionType}, ext); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to get extension: %w", err)
+		}
+
+		cluster := &extensionsv1alpha1.Cluster{}
+		if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, cluster); err != nil {
+			return nil, fmt.Errorf("failed to get cluster: %w", err)
+		}
+		return cluster, nil
+	}
+
+	return ext, nil
+}
+
+// RecordForNamespace records a normal event concerning the shoot of the given namespace on the object returned by
+// ObjectForNamespace. Events are informational only, hence failures are logged but not returned. No event is recorded
+// for dry-run admission requests, the webhooks are registered without side effects.
+func RecordForNamespace(ctx context.Context, log logr.Logger, reader client.Reader, recorder events.EventRecorder, namespace, reason, action, note string, args ...any) {
+	recordForNamespace(ctx, log, reader, recorder, namespace, corev1.EventTypeNormal, reason, action, note, args...)
+}
+
+// RecordWarningForNamespace records a warning event like RecordForNamespace.
+func RecordWarningForNamespace(ctx context.Context, log logr.Logger, reader client.Reader, recorder events.EventRecorder, namespace, reason, action, note string, args ...any) {
+	recordForNamespace(ctx, log, reader, recorder, namespace, corev1.EventTypeWarning, reason, action, note, args...)
+}
+
+func recordForNamespace(ctx context.Context, log logr.Logger, reader client.Reader, recorder events.EventRecorder, namespace, eventType, reason, action, note string, args ...any) {
+	if IsDryRun(ctx) {
+		return
+	}
+
+	obj, err := ObjectForNamespace(ctx, reader, namespace)
+	if err != nil {
+		log.Error(err, "Failed to record event", "reason", reason)
+		return
+	}
+
+	recorder.Eventf(obj, nil, eventType, reason, action, note, args...)
+}
+
+// IsDryRun returns true if the context carries an admission request which is a dry run.
+func IsDryRun(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && ptr.Deref(req.DryRun, false)
+}
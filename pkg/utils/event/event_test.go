@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_test
+
+import (
+	"context"
+
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/event"
+)
+
+var _ = Describe("Event", func() {
+	var (
+		ctx        context.Context
+		fakeClient client.Client
+		namespace  string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		fakeClient = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+
+		namespace = "shoot--test--local"
+		Expect(fakeClient.Create(ctx, &extensionsv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+	})
+
+	Describe("#ObjectForNamespace", func() {
+		It("should return the cluster if the extension does not exist", func() {
+			obj, err := ObjectForNamespace(ctx, fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(obj).To(BeAssignableToTypeOf(&extensionsv1alpha1.Cluster{}))
+			Expect(obj.GetName()).To(Equal(namespace))
+		})
+
+		It("should return the extension if it exists", func() {
+			Expect(fakeClient.Create(ctx, &extensionsv1alpha1.Extension{ObjectMeta: metav1.ObjectMeta{Name: "image-rewriter", Namespace: namespace}})).To(Succeed())
+
+			obj, err := ObjectForNamespace(ctx, fakeClient, namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(obj).To(BeAssignableToTypeOf(&extensionsv1alpha1.Extension{}))
+			Expect(obj.GetName()).To(Equal("image-rewriter"))
+		})
+
+		It("should fail if neither the extension nor the cluster exist", func() {
+			_, err := ObjectForNamespace(ctx, fakeClient, "other-namespace")
+			Expect(err).To(MatchError(ContainSubstring("failed to get cluster")))
+		})
+	})
+
+	Describe("#RecordForNamespace", func() {
+		It("should record the event", func() {
+			recorder := events.NewFakeRecorder(1)
+
+			RecordForNamespace(ctx, logr.Discard(), fakeClient, recorder, namespace, ReasonImagesRewritten, ActionMutate, "Rewrote %d images", 2)
+			Expect(recorder.Events).To(Receive(Equal("Normal ImagesRewritten Rewrote 2 images")))
+		})
+
+		It("should not record the event if the object cannot be determined", func() {
+			recorder := events.NewFakeRecorder(1)
+
+			RecordForNamespace(ctx, logr.Discard(), fakeClient, recorder, "other-namespace", ReasonImagesRewritten, ActionMutate, "Rewrote %d images", 2)
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should not record the event for a dry-run admission request", func() {
+			recorder := events.NewFakeRecorder(1)
+			ctx = admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{DryRun: ptr.To(true)}})
+
+			RecordForNamespace(ctx, logr.Discard(), fakeClient, recorder, namespace, ReasonImagesRewritten, ActionMutate, "Rewrote %d images", 2)
+			Expect(recorder.Events).NotTo(Receive())
+		})
+	})
+
+	Describe("#RecordWarningForNamespace", func() {
+		It("should record a warning event", func() {
+			recorder := events.NewFakeRecorder(1)
+
+			RecordWarningForNamespace(ctx, logr.Discard(), fakeClient, recorder, namespace, ReasonRegistryConfigurationConflict, ActionMutate, "Server %q differs", "https://server1")
+			Expect(recorder.Events).To(Receive(Equal(`Warning RegistryConfigurationConflict Server "https://server1" differs`)))
+		})
+	})
+})
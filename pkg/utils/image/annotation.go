@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationOriginalImages is the annotation which records the images of an object before they were rewritten.
+// Its value is a JSON map, e.g. from container name to original image for pods. Ephemeral containers are not recorded,
+// because the 'pods/ephemeralcontainers' subresource ignores changes of the metadata.
+const AnnotationOriginalImages = "image-rewriter.extensions.gardener.cloud/original-images"
+
+// GetOriginalImages returns the original images recorded in the AnnotationOriginalImages annotation of the object.
+func GetOriginalImages(obj metav1.Object) (map[string]string, error) {
+	originalImages := map[string]string{}
+
+	value, ok := obj.GetAnnotations()[AnnotationOriginalImages]
+	if !ok {
+		return originalImages, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &originalImages); err != nil {
+		return nil, fmt.Errorf("failed to decode annotation %s: %w", AnnotationOriginalImages, err)
+	}
+	return originalImages, nil
+}
+
+// SetOriginalImages replaces the AnnotationOriginalImages annotation of the object with the given original images.
+// The annotation is removed if there are no original images, hence it never contains entries of earlier rewrites which
+// do not apply anymore.
+func SetOriginalImages(obj metav1.Object, originalImages map[string]string) error {
+	annotations := maps.Clone(obj.GetAnnotations())
+
+	if len(originalImages) == 0 {
+		if _, ok := annotations[AnnotationOriginalImages]; ok {
+			delete(annotations, AnnotationOriginalImages)
+			obj.SetAnnotations(annotations)
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(originalImages)
+	if err != nil {
+		return fmt.Errorf("failed to encode annotation %s: %w", AnnotationOriginalImages, err)
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationOriginalImages] = string(value)
+	obj.SetAnnotations(annotations)
+	return nil
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+var _ = Describe("Annotation", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}}
+	})
+
+	Describe("#SetOriginalImages", func() {
+		It("should not add the annotation if there are no original images", func() {
+			Expect(SetOriginalImages(pod, nil)).To(Succeed())
+			Expect(pod.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+		})
+
+		It("should replace the original images of the annotation", func() {
+			Expect(SetOriginalImages(pod, map[string]string{"app": "nginx:1.26", "init": "busybox"})).To(Succeed())
+			Expect(SetOriginalImages(pod, map[string]string{"app": "nginx:1.27"})).To(Succeed())
+
+			Expect(pod.Annotations).To(Equal(map[string]string{
+				"foo":                    "bar",
+				AnnotationOriginalImages: `{"app":"nginx:1.27"}`,
+			}))
+			Expect(GetOriginalImages(pod)).To(Equal(map[string]string{"app": "nginx:1.27"}))
+		})
+
+		It("should remove the annotation if there are no original images anymore", func() {
+			pod.Annotations[AnnotationOriginalImages] = `{"app":"nginx"}`
+
+			Expect(SetOriginalImages(pod, map[string]string{})).To(Succeed())
+			Expect(pod.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+		})
+	})
+
+	Describe("#GetOriginalImages", func() {
+		It("should return an empty map if the annotation is missing", func() {
+			Expect(GetOriginalImages(pod)).To(BeEmpty())
+		})
+
+		It("should fail if the annotation cannot be decoded", func() {
+			pod.Annotations[AnnotationOriginalImages] = "foo"
+
+			_, err := GetOriginalImages(pod)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
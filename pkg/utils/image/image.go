@@ -5,115 +5,221 @@
 package image
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/selector"
 )
 
 // Configuration defines the interface for operating on image configurations.
 type Configuration interface {
 	// FindTargetImage returns the target image for a given source image, provider, and region.
 	FindTargetImage(source string, provider string, region string) string
+	// Lookup returns the target image and the matching source rule for a given source image, provider, and region.
+	Lookup(source string, provider string, region string) Result
 	// HasOverwrite checks if there is an overwrite for the given provider and region.
 	HasOverwrite(provider string, region string) bool
+	// Overwrites returns the overwrites which apply to the given provider and region in the order they are matched.
+	Overwrites(provider string, region string) []Overwrite
+}
+
+// Overwrite is an overwrite with the target which applies to a provider and region.
+type Overwrite struct {
+	// Source is the configured source of the overwrite.
+	Source v1alpha1.Image
+	// Target is the target for the provider and region. It is a prefix if the source is a prefix, otherwise an image.
+	Target v1alpha1.Image
+}
+
+// Result is the result of looking up the target image of a source image.
+type Result struct {
+	// Target is the target image. It is empty if the source image is not rewritten.
+	Target string
+	// Rule is the configured source of the overwrite which matched the source image, i.e. its image, prefix or regex.
+	// If the source image is not rewritten, it is the first overwrite which matched regardless of provider and region.
+	// It is empty if no overwrite matched the source image.
+	Rule string
+	// RuleSource is the configured source of the overwrite of Rule. It is only set if Rule is not empty.
+	RuleSource v1alpha1.Image
 }
 
 type configuration struct {
+	// overwrites are sorted in the order they are matched, see MatchOrder.
 	overwrites []overwrite
+	// index finds the overwrites which may match an image in the 'LongestPrefix' matching mode.
+	index *index
 }
 
 type overwrite struct {
-	prefixed         bool
-	source           string
-	providerToTarget map[string]target
-}
-
-type target struct {
-	regionToTarget map[string]string
-	globalTarget   string
+	prefixed     bool
+	pattern      *regexp.Regexp
+	rule         string
+	sourceConfig v1alpha1.Image
+	source       string
+	targets      selector.Selector[string]
 }
 
 // HasOverwrite checks if there is an overwrite for the given provider and region.
 func (c *configuration) HasOverwrite(provider string, region string) bool {
 	for _, overwrite := range c.overwrites {
-		if target, exists := overwrite.providerToTarget[provider]; exists {
-			if _, exists := target.regionToTarget[region]; exists || target.globalTarget != "" {
-				return true
-			}
+		if overwrite.targetFor(provider, region) != "" {
+			return true
 		}
 	}
 	return false
 }
 
-// FindTargetImage returns the target image for a given source image, provider, and region.
-func (c *configuration) FindTargetImage(sourceImage string, provider string, region string) string {
+// Overwrites returns the overwrites which apply to the given provider and region in the order they are matched.
+func (c *configuration) Overwrites(provider string, region string) []Overwrite {
+	var overwrites []Overwrite
 	for _, overwrite := range c.overwrites {
-		var imageSuffix string
+		targetImage := overwrite.targetFor(provider, region)
+		if targetImage == "" {
+			continue
+		}
+
+		result := Overwrite{Source: *overwrite.sourceConfig.DeepCopy()}
 		if overwrite.prefixed {
+			result.Target.Prefix = &targetImage
+		} else {
+			result.Target.Image = &targetImage
+		}
+		overwrites = append(overwrites, result)
+	}
+	return overwrites
+}
+
+// targetFor returns the target of the overwrite for the given provider and region, see selector.Selector for the
+// precedence of the targets.
+func (o overwrite) targetFor(provider string, region string) string {
+	target, _ := o.targets.Select(provider, region)
+	return target
+}
+
+// FindTargetImage returns the target image for a given source image, provider, and region.
+// The source image is normalised before it is matched against 'image' and 'prefix' sources, see
+// ParseNormalizedReference. Images which cannot be parsed are matched as they are. 'regex' sources are matched against
+// the source image as it is.
+func (c *configuration) FindTargetImage(sourceImage string, provider string, region string) string {
+	return c.Lookup(sourceImage, provider, region).Target
+}
+
+// Lookup returns the target image and the matching source rule for a given source image, provider, and region.
+func (c *configuration) Lookup(sourceImage string, provider string, region string) Result {
+	rawImage := sourceImage
+	reference, err := ParseNormalizedReference(sourceImage)
+	if err == nil {
+		sourceImage = reference.String()
+	}
+
+	var result Result
+	// lookup sets the result for the given overwrite and returns true if the overwrite rewrites the source image.
+	lookup := func(overwrite overwrite) bool {
+		var (
+			imageSuffix string
+			submatches  []int
+		)
+		switch {
+		case overwrite.pattern != nil:
+			if submatches = overwrite.pattern.FindStringSubmatchIndex(rawImage); submatches == nil {
+				return false
+			}
+		case overwrite.prefixed:
 			if !strings.HasPrefix(sourceImage, overwrite.source) {
-				continue
+				return false
 			}
 			imageSuffix = strings.TrimPrefix(sourceImage, overwrite.source)
-		} else {
-			if overwrite.source != sourceImage {
-				continue
+		default:
+			// An image source without digest matches the source image regardless of its digest.
+			if overwrite.source != sourceImage && (err != nil || overwrite.source != strings.TrimSuffix(sourceImage, "@"+reference.Digest)) {
+				return false
 			}
 		}
 
-		target, providerConfigured := overwrite.providerToTarget[provider]
-		if !providerConfigured {
-			continue
+		if result.Rule == "" {
+			result.Rule, result.RuleSource = overwrite.rule, overwrite.sourceConfig
 		}
 
-		targetImage := target.regionToTarget[region]
+		targetImage := overwrite.targetFor(provider, region)
 		if targetImage == "" {
-			targetImage = target.globalTarget
+			return false
 		}
 
-		if targetImage == "" {
-			continue
+		result.Rule, result.RuleSource = overwrite.rule, overwrite.sourceConfig
+		switch {
+		case overwrite.pattern != nil:
+			result.Target = string(overwrite.pattern.ExpandString(nil, targetImage, rawImage, submatches))
+		case overwrite.prefixed:
+			result.Target = targetImage + imageSuffix
+		case err == nil:
+			// An image source without digest matches images pinned by digest, they stay pinned.
+			result.Target = keepTagAndDigest(targetImage, reference, overwrite.source != sourceImage)
+		default:
+			result.Target = targetImage
 		}
+		return true
+	}
 
-		if overwrite.prefixed {
-			return targetImage + imageSuffix
+	if c.index == nil {
+		for _, overwrite := range c.overwrites {
+			if lookup(overwrite) {
+				break
+			}
 		}
-		return targetImage
+		return result
 	}
 
-	return ""
+	var digest string
+	if err == nil {
+		digest = reference.Digest
+	}
+	for _, i := range c.index.candidates(sourceImage, digest) {
+		if lookup(c.overwrites[i]) {
+			break
+		}
+	}
+	return result
 }
 
 // NewImageConfiguration creates a new image configuration implementation.
 func NewImageConfiguration(config *v1alpha1.Configuration) Configuration {
 	overwrites := make([]overwrite, 0, len(config.Overwrites))
-	for _, o := range config.Overwrites {
-		providerToTarget := make(map[string]target)
-		for _, t := range o.Targets {
-			if _, exists := providerToTarget[t.Provider]; !exists {
-				providerToTarget[t.Provider] = target{
-					regionToTarget: make(map[string]string),
-				}
-			}
+	for _, i := range MatchOrder(config) {
+		o := config.Overwrites[i]
 
-			for _, region := range t.Regions {
-				providerToTarget[t.Provider].regionToTarget[region] = prefixOrImage(t.Image)
-			}
+		var targets selector.Selector[string]
+		for _, t := range o.Targets {
+			targets.Add(t.Provider, t.Regions, prefixOrImage(t.Image))
+		}
 
-			if len(t.Regions) == 0 {
-				providerToTarget[t.Provider] = target{globalTarget: prefixOrImage(t.Image)}
+		var pattern *regexp.Regexp
+		if o.Source.Regex != nil {
+			var err error
+			// The configuration is validated beforehand, skip the overwrite if the expression is invalid nevertheless.
+			if pattern, err = CompileSourcePattern(*o.Source.Regex); err != nil {
+				continue
 			}
 		}
 
 		overwrites = append(overwrites, overwrite{
-			prefixed:         o.Source.Prefix != nil,
-			source:           prefixOrImage(o.Source),
-			providerToTarget: providerToTarget,
+			prefixed:     o.Source.Prefix != nil,
+			pattern:      pattern,
+			rule:         prefixOrImage(o.Source),
+			sourceConfig: o.Source,
+			source:       normalizeSource(o.Source),
+			targets:      targets,
 		})
 	}
 
-	return &configuration{
+	conf := &configuration{
 		overwrites: overwrites,
 	}
+	if config.Matching == v1alpha1.MatchingModeLongestPrefix {
+		conf.index = newIndex(overwrites)
+	}
+	return conf
 }
 
 func prefixOrImage(image v1alpha1.Image) string {
@@ -123,5 +229,94 @@ func prefixOrImage(image v1alpha1.Image) string {
 	if image.Image != nil {
 		return *image.Image
 	}
+	if image.Regex != nil {
+		return *image.Regex
+	}
 	return ""
 }
+
+func normalizeSource(source v1alpha1.Image) string {
+	if source.Prefix != nil {
+		return NormalizePrefix(*source.Prefix)
+	}
+	if source.Image != nil {
+		if reference, err := ParseNormalizedReference(*source.Image); err == nil {
+			return reference.String()
+		}
+	}
+	return prefixOrImage(source)
+}
+
+// CompileSourcePattern compiles the regular expression of an overwrite source. The expression has to match the whole
+// source image as it is, i.e. it is not normalised.
+func CompileSourcePattern(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// UnknownGroupReferences returns the capture group references of the given target template, e.g. '$1' or '${name}',
+// which do not exist in the given pattern.
+func UnknownGroupReferences(pattern *regexp.Regexp, template string) []string {
+	var unknown []string
+
+	for {
+		i := strings.IndexByte(template, '$')
+		if i < 0 {
+			return unknown
+		}
+		template = template[i+1:]
+
+		// '$$' is an escaped '$'.
+		if strings.HasPrefix(template, "$") {
+			template = template[1:]
+			continue
+		}
+
+		name, rest, ok := extractGroupReference(template)
+		if !ok {
+			continue
+		}
+		template = rest
+
+		if !hasGroup(pattern, name) {
+			unknown = append(unknown, name)
+		}
+	}
+}
+
+// extractGroupReference follows the syntax of regexp.Regexp.Expand, i.e. a reference is either '${name}' or '$name'
+// where the name is the longest sequence of letters, digits and underscores.
+func extractGroupReference(template string) (string, string, bool) {
+	braced := strings.HasPrefix(template, "{")
+	if braced {
+		template = template[1:]
+	}
+
+	i := 0
+	for i < len(template) && isGroupNameChar(template[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", "", false
+	}
+
+	name, rest := template[:i], template[i:]
+	if braced {
+		if !strings.HasPrefix(rest, "}") {
+			return "", "", false
+		}
+		rest = rest[1:]
+	}
+
+	return name, rest, true
+}
+
+func isGroupNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func hasGroup(pattern *regexp.Regexp, name string) bool {
+	if index, err := strconv.Atoi(name); err == nil {
+		return index >= 0 && index <= pattern.NumSubexp()
+	}
+	return pattern.SubexpIndex(name) >= 0
+}
@@ -112,6 +112,264 @@ var _ = Describe("Image", func() {
 		})
 	})
 
+	Describe("#FindTargetImage with normalised references", func() {
+		const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+		BeforeEach(func() {
+			config.Overwrites = []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To("nginx")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{
+							Image:    v1alpha1.Image{Image: ptr.To("mirror.example/nginx")},
+							Provider: "local",
+						},
+					},
+				},
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("gardener/")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{
+							Image:    v1alpha1.Image{Prefix: ptr.To("mirror.example/gardener/")},
+							Provider: "local",
+						},
+					},
+				},
+			}
+
+			imageConfig = NewImageConfiguration(config)
+		})
+
+		It("should match all spellings of the same image", func() {
+			for _, sourceImage := range []string{"nginx", "nginx:latest", "docker.io/library/nginx", "index.docker.io/library/nginx:latest"} {
+				Expect(imageConfig.FindTargetImage(sourceImage, "local", "west")).To(Equal("mirror.example/nginx:latest"), sourceImage)
+			}
+		})
+
+		It("should keep the digest of the source image", func() {
+			Expect(imageConfig.FindTargetImage("nginx@"+digest, "local", "west")).To(BeEmpty())
+			Expect(imageConfig.FindTargetImage("nginx:latest@"+digest, "local", "west")).To(Equal("mirror.example/nginx:latest@" + digest))
+		})
+
+		It("should keep the digest of the source image for targets with tag", func() {
+			config.Overwrites[0].Targets[0].Image.Image = ptr.To("mirror.example/nginx:mirrored")
+			imageConfig = NewImageConfiguration(config)
+
+			Expect(imageConfig.FindTargetImage("nginx", "local", "west")).To(Equal("mirror.example/nginx:mirrored"))
+			Expect(imageConfig.FindTargetImage("nginx:latest@"+digest, "local", "west")).To(Equal("mirror.example/nginx:mirrored@" + digest))
+		})
+
+		It("should not match other tags", func() {
+			Expect(imageConfig.FindTargetImage("nginx:1.27", "local", "west")).To(BeEmpty())
+		})
+
+		It("should match normalised prefixes", func() {
+			Expect(imageConfig.FindTargetImage("gardener/image:v1", "local", "west")).To(Equal("mirror.example/gardener/image:v1"))
+			Expect(imageConfig.FindTargetImage("docker.io/gardener/image@"+digest, "local", "west")).To(Equal("mirror.example/gardener/image@" + digest))
+		})
+
+		It("should match prefixes of official images", func() {
+			for _, prefix := range []string{"docker.io/library/nginx", "index.docker.io/library/nginx", "nginx"} {
+				config.Overwrites = []v1alpha1.ImageOverwrite{{
+					Source:  v1alpha1.Image{Prefix: ptr.To(prefix)},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example/library/nginx")}, Provider: "local"}},
+				}}
+				imageConfig = NewImageConfiguration(config)
+
+				Expect(imageConfig.FindTargetImage("nginx:1", "local", "west")).To(Equal("mirror.example/library/nginx:1"), prefix)
+				Expect(imageConfig.FindTargetImage("docker.io/nginx:1", "local", "west")).To(Equal("mirror.example/library/nginx:1"), prefix)
+				Expect(imageConfig.FindTargetImage("docker.io/library/nginx:1", "local", "west")).To(Equal("mirror.example/library/nginx:1"), prefix)
+			}
+		})
+
+		It("should match prefixes of docker organisations", func() {
+			for _, prefix := range []string{"docker.io/bitnami", "index.docker.io/bitnami"} {
+				config.Overwrites = []v1alpha1.ImageOverwrite{{
+					Source:  v1alpha1.Image{Prefix: ptr.To(prefix)},
+					Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Prefix: ptr.To("mirror.example/bitnami")}, Provider: "local"}},
+				}}
+				imageConfig = NewImageConfiguration(config)
+
+				Expect(imageConfig.FindTargetImage("bitnami/redis:7", "local", "west")).To(Equal("mirror.example/bitnami/redis:7"), prefix)
+				Expect(imageConfig.FindTargetImage("docker.io/bitnami/redis:7", "local", "west")).To(Equal("mirror.example/bitnami/redis:7"), prefix)
+				Expect(imageConfig.FindTargetImage("bitnami:1", "local", "west")).To(BeEmpty(), prefix)
+			}
+		})
+	})
+
+	Describe("#FindTargetImage with regex", func() {
+		BeforeEach(func() {
+			config.Overwrites = []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Regex: ptr.To(`registry\.k8s\.io/([^:@]+)(.*)`)},
+					Targets: []v1alpha1.TargetConfiguration{
+						{
+							Image:    v1alpha1.Image{Image: ptr.To("mirror.example/k8s/${1}-mirror$2")},
+							Provider: "local",
+						},
+					},
+				},
+				{
+					Source: v1alpha1.Image{Regex: ptr.To(`quay\.io/(?P<org>[^/]+)/(?P<name>.+)`)},
+					Targets: []v1alpha1.TargetConfiguration{
+						{
+							Image:    v1alpha1.Image{Image: ptr.To("mirror.example/quay/${name}/${org}")},
+							Provider: "local",
+							Regions:  []string{"west"},
+						},
+					},
+				},
+			}
+
+			imageConfig = NewImageConfiguration(config)
+		})
+
+		It("should replace capture group references", func() {
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "local", "west")).To(Equal("mirror.example/k8s/pause-mirror:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/coredns/coredns", "local", "west")).To(Equal("mirror.example/k8s/coredns/coredns-mirror"))
+		})
+
+		It("should match the image as it is, not the normalised image", func() {
+			config.Overwrites = []v1alpha1.ImageOverwrite{{
+				Source:  v1alpha1.Image{Regex: ptr.To(`nginx:(.*)`)},
+				Targets: []v1alpha1.TargetConfiguration{{Image: v1alpha1.Image{Image: ptr.To("mirror.example/nginx:$1")}, Provider: "local"}},
+			}}
+			imageConfig = NewImageConfiguration(config)
+
+			Expect(imageConfig.FindTargetImage("nginx:1.27", "local", "west")).To(Equal("mirror.example/nginx:1.27"))
+			Expect(imageConfig.FindTargetImage("docker.io/library/nginx:1.27", "local", "west")).To(BeEmpty())
+		})
+
+		It("should replace named capture group references", func() {
+			Expect(imageConfig.FindTargetImage("quay.io/prometheus/node-exporter:v1", "local", "west")).To(Equal("mirror.example/quay/node-exporter:v1/prometheus"))
+			Expect(imageConfig.FindTargetImage("quay.io/prometheus/node-exporter:v1", "local", "east")).To(BeEmpty())
+		})
+
+		It("should only match the whole image", func() {
+			Expect(imageConfig.FindTargetImage("docker.io/registry.k8s.io/pause:3.10", "local", "west")).To(BeEmpty())
+		})
+	})
+
+	Describe("#Lookup", func() {
+		BeforeEach(func() {
+			imageConfig = NewImageConfiguration(config)
+		})
+
+		It("should return the target image and the matching rule", func() {
+			Expect(imageConfig.Lookup(image, "local", "west")).To(Equal(Result{Target: *imageReplacement("west"), Rule: image, RuleSource: v1alpha1.Image{Image: &image}}))
+		})
+
+		It("should return the matching rule if there is no target for the provider and region", func() {
+			Expect(imageConfig.Lookup(image, "local", "central")).To(Equal(Result{Rule: image, RuleSource: v1alpha1.Image{Image: &image}}))
+		})
+
+		It("should return an empty result if no rule matches", func() {
+			Expect(imageConfig.Lookup("registry.example.com/other:latest", "local", "west")).To(Equal(Result{}))
+		})
+	})
+
+	Describe("#Lookup with longest prefix matching", func() {
+		var lpmConfig *v1alpha1.Configuration
+
+		target := func(target v1alpha1.Image, regions ...string) []v1alpha1.TargetConfiguration {
+			return []v1alpha1.TargetConfiguration{{Image: target, Provider: "local", Regions: regions}}
+		}
+
+		BeforeEach(func() {
+			lpmConfig = &v1alpha1.Configuration{
+				Matching: v1alpha1.MatchingModeLongestPrefix,
+				Overwrites: []v1alpha1.ImageOverwrite{
+					{Source: v1alpha1.Image{Regex: ptr.To(`registry\.k8s\.io/(.*)`)}, Targets: target(v1alpha1.Image{Image: ptr.To("regex.example.com/$1")})},
+					{Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")}, Targets: target(v1alpha1.Image{Prefix: ptr.To("mirror.example.com/k8s")})},
+					{Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io/kube-")}, Targets: target(v1alpha1.Image{Prefix: ptr.To("mirror.example.com/kube-")}, "west")},
+					{Source: v1alpha1.Image{Image: ptr.To("registry.k8s.io/pause:3.10")}, Targets: target(v1alpha1.Image{Image: ptr.To("pause.example.com/pause:3.10")})},
+					{Source: v1alpha1.Image{Image: ptr.To("registry.k8s.io/pause:3.10@sha256:0000000000000000000000000000000000000000000000000000000000000000")}, Targets: target(v1alpha1.Image{Image: ptr.To("digest.example.com/pause:3.10")})},
+				},
+			}
+			imageConfig = NewImageConfiguration(lpmConfig)
+		})
+
+		It("should prefer the most specific prefix regardless of the order", func() {
+			Expect(imageConfig.Lookup("registry.k8s.io/kube-proxy:v1.33.0", "local", "west")).To(Equal(Result{Target: "mirror.example.com/kube-proxy:v1.33.0", Rule: "registry.k8s.io/kube-", RuleSource: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io/kube-")}}))
+			Expect(imageConfig.Lookup("registry.k8s.io/coredns:v1.12.0", "local", "west")).To(Equal(Result{Target: "mirror.example.com/k8s/coredns:v1.12.0", Rule: "registry.k8s.io", RuleSource: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")}}))
+		})
+
+		It("should fall back to a shorter prefix if the most specific one has no target for the region", func() {
+			Expect(imageConfig.Lookup("registry.k8s.io/kube-proxy:v1.33.0", "local", "east")).To(Equal(Result{Target: "mirror.example.com/k8s/kube-proxy:v1.33.0", Rule: "registry.k8s.io", RuleSource: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")}}))
+		})
+
+		It("should prefer image sources over prefixes and image sources with digest over those without", func() {
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "local", "west")).To(Equal("pause.example.com/pause:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10@sha256:0000000000000000000000000000000000000000000000000000000000000000", "local", "west")).To(Equal("digest.example.com/pause:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10@sha256:1111111111111111111111111111111111111111111111111111111111111111", "local", "west")).To(Equal("pause.example.com/pause:3.10@sha256:1111111111111111111111111111111111111111111111111111111111111111"))
+		})
+
+		It("should match regex sources last", func() {
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/etcd:3.5", "other", "west")).To(BeEmpty())
+
+			lpmConfig.Overwrites[1].Targets = nil
+			imageConfig = NewImageConfiguration(lpmConfig)
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/etcd:3.5", "local", "west")).To(Equal("regex.example.com/etcd:3.5"))
+		})
+
+		It("should return the overwrites in the order they are matched", func() {
+			Expect(MatchOrder(lpmConfig)).To(Equal([]int{4, 3, 2, 1, 0}))
+			Expect(imageConfig.Overwrites("local", "west")).To(HaveExactElements(
+				HaveField("Source.Image", HaveValue(HavePrefix("registry.k8s.io/pause:3.10@"))),
+				HaveField("Source.Image", HaveValue(Equal("registry.k8s.io/pause:3.10"))),
+				HaveField("Source.Prefix", HaveValue(Equal("registry.k8s.io/kube-"))),
+				HaveField("Source.Prefix", HaveValue(Equal("registry.k8s.io"))),
+				HaveField("Source.Regex", Not(BeNil())),
+			))
+		})
+
+		It("should keep the configured order in the first match mode", func() {
+			lpmConfig.Matching = v1alpha1.MatchingModeFirstMatch
+			Expect(MatchOrder(lpmConfig)).To(Equal([]int{0, 1, 2, 3, 4}))
+			Expect(NewImageConfiguration(lpmConfig).FindTargetImage("registry.k8s.io/kube-proxy:v1.33.0", "local", "west")).To(Equal("regex.example.com/kube-proxy:v1.33.0"))
+		})
+	})
+
+	Describe("#FindTargetImage with provider and region patterns", func() {
+		BeforeEach(func() {
+			config.Overwrites = []v1alpha1.ImageOverwrite{
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.k8s.io")},
+					Targets: []v1alpha1.TargetConfiguration{
+						{Image: v1alpha1.Image{Prefix: ptr.To("global.example.com/k8s")}, Provider: "aws"},
+						{Image: v1alpha1.Image{Prefix: ptr.To("eu.example.com/k8s")}, Provider: "aws", Regions: []string{"eu-*"}},
+						{Image: v1alpha1.Image{Prefix: ptr.To("ireland.example.com/k8s")}, Provider: "aws", Regions: []string{"eu-west-1"}},
+						{Image: v1alpha1.Image{Prefix: ptr.To("us.example.com/k8s")}, Provider: "*", Regions: []string{"us-*"}},
+					},
+				},
+			}
+			imageConfig = NewImageConfiguration(config)
+		})
+
+		It("should prefer the exact region over a region pattern over any region", func() {
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "aws", "eu-west-1")).To(Equal("ireland.example.com/k8s/pause:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "aws", "eu-central-1")).To(Equal("eu.example.com/k8s/pause:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "aws", "us-east-1")).To(Equal("global.example.com/k8s/pause:3.10"))
+		})
+
+		It("should fall back to provider patterns", func() {
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "gcp", "us-east1")).To(Equal("us.example.com/k8s/pause:3.10"))
+			Expect(imageConfig.FindTargetImage("registry.k8s.io/pause:3.10", "gcp", "europe-west1")).To(BeEmpty())
+			Expect(imageConfig.HasOverwrite("azure", "us-west")).To(BeTrue())
+			Expect(imageConfig.HasOverwrite("azure", "westeurope")).To(BeFalse())
+		})
+	})
+
+	Describe("#UnknownGroupReferences", func() {
+		It("should return references to groups which do not exist", func() {
+			pattern, err := CompileSourcePattern(`(a)(?P<b>b)`)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(UnknownGroupReferences(pattern, "$0 $1 ${2} $b ${b}x $$3 $")).To(BeEmpty())
+			Expect(UnknownGroupReferences(pattern, "$3 ${c} $bx ${4}")).To(Equal([]string{"3", "c", "bx", "4"}))
+		})
+	})
+
 	Describe("#HasOverwrite", func() {
 		BeforeEach(func() {
 			imageConfig = NewImageConfiguration(config)
@@ -131,6 +389,65 @@ var _ = Describe("Image", func() {
 			Expect(imageConfig.HasOverwrite("local2", "central")).To(BeFalse())
 		})
 	})
+
+	Describe("#Overwrites", func() {
+		BeforeEach(func() {
+			config.Overwrites = append(config.Overwrites, v1alpha1.ImageOverwrite{
+				Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com/prefix")},
+				Targets: []v1alpha1.TargetConfiguration{
+					{
+						Image:    v1alpha1.Image{Prefix: imageReplacementPrefix("west")},
+						Provider: "local",
+						Regions:  []string{"west"},
+					},
+				},
+			})
+			imageConfig = NewImageConfiguration(config)
+		})
+
+		It("should return the overwrites with the targets for the provider and region", func() {
+			Expect(imageConfig.Overwrites("local", "west")).To(Equal([]Overwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To(image)},
+					Target: v1alpha1.Image{Image: imageReplacement("west")},
+				},
+				{
+					Source: v1alpha1.Image{Prefix: ptr.To("registry.example.com/prefix")},
+					Target: v1alpha1.Image{Prefix: imageReplacementPrefix("west")},
+				},
+			}))
+			Expect(imageConfig.Overwrites("local", "east")).To(Equal([]Overwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To(image)},
+					Target: v1alpha1.Image{Image: imageReplacement("east")},
+				},
+			}))
+		})
+
+		It("should return the global target of the provider for any region", func() {
+			Expect(imageConfig.Overwrites("global", "any-region")).To(Equal([]Overwrite{
+				{
+					Source: v1alpha1.Image{Image: ptr.To(image)},
+					Target: v1alpha1.Image{Image: imageReplacement("global")},
+				},
+			}))
+		})
+
+		It("should return no overwrites if none applies to the provider and region", func() {
+			Expect(imageConfig.Overwrites("local", "central")).To(BeEmpty())
+		})
+
+		It("should keep the targets for specific regions if a target for any region of the provider follows", func() {
+			config.Overwrites[0].Targets = append(config.Overwrites[0].Targets, v1alpha1.TargetConfiguration{
+				Image:    v1alpha1.Image{Image: imageReplacement("any")},
+				Provider: "local",
+			})
+			imageConfig = NewImageConfiguration(config)
+
+			Expect(imageConfig.FindTargetImage(image, "local", "west")).To(Equal(*imageReplacement("west")))
+			Expect(imageConfig.FindTargetImage(image, "local", "central")).To(Equal(*imageReplacement("any")))
+		})
+	})
 })
 
 func imageReplacementPrefix(region string) *string {
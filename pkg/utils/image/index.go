@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+)
+
+// MatchOrder returns the indices of the overwrites of the configuration in the order they are matched against images.
+// In the 'FirstMatch' mode, it is the configured order. In the 'LongestPrefix' mode, 'image' sources with digest come
+// first, followed by 'image' sources without digest, 'prefix' sources from the longest to the shortest normalised
+// prefix and finally 'regex' sources. Overwrites of the same precedence keep their configured order.
+func MatchOrder(config *v1alpha1.Configuration) []int {
+	order := make([]int, len(config.Overwrites))
+	for i := range order {
+		order[i] = i
+	}
+
+	if config.Matching != v1alpha1.MatchingModeLongestPrefix {
+		return order
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		classA, lengthA := precedence(config.Overwrites[a].Source)
+		classB, lengthB := precedence(config.Overwrites[b].Source)
+		if classA != classB {
+			return classA - classB
+		}
+		return lengthB - lengthA
+	})
+	return order
+}
+
+// precedence returns the class of the source, lower classes are matched first, and the length of its normalised
+// prefix, longer prefixes are matched first.
+func precedence(source v1alpha1.Image) (int, int) {
+	switch {
+	case source.Image != nil:
+		if reference, err := ParseNormalizedReference(*source.Image); err == nil && reference.Digest != "" {
+			return 0, 0
+		}
+		return 1, 0
+	case source.Prefix != nil:
+		return 2, len(NormalizePrefix(*source.Prefix))
+	default:
+		return 3, 0
+	}
+}
+
+// index finds the overwrites which may match an image without scanning all overwrites. It refers to the overwrites by
+// their position in the match order.
+type index struct {
+	// images maps the normalised source images to the positions of their overwrites.
+	images map[string][]int
+	// prefixes is a trie over the characters of the normalised source prefixes.
+	prefixes *trieNode
+	// patterns are the positions of the overwrites with 'regex' source, they cannot be indexed.
+	patterns []int
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	// overwrites are the positions of the overwrites whose prefix ends at this node.
+	overwrites []int
+}
+
+func newIndex(overwrites []overwrite) *index {
+	idx := &index{
+		images:   map[string][]int{},
+		prefixes: &trieNode{},
+	}
+
+	for i, overwrite := range overwrites {
+		switch {
+		case overwrite.pattern != nil:
+			idx.patterns = append(idx.patterns, i)
+		case overwrite.prefixed:
+			node := idx.prefixes
+			for j := 0; j < len(overwrite.source); j++ {
+				child, ok := node.children[overwrite.source[j]]
+				if !ok {
+					if node.children == nil {
+						node.children = map[byte]*trieNode{}
+					}
+					child = &trieNode{}
+					node.children[overwrite.source[j]] = child
+				}
+				node = child
+			}
+			node.overwrites = append(node.overwrites, i)
+		default:
+			idx.images[overwrite.source] = append(idx.images[overwrite.source], i)
+		}
+	}
+
+	return idx
+}
+
+// candidates returns the positions of the overwrites which may match the given normalised image in the order they are
+// matched. The digest is the digest of the image, if any.
+func (idx *index) candidates(sourceImage, digest string) []int {
+	var candidates []int
+
+	candidates = append(candidates, idx.images[sourceImage]...)
+	if digest != "" {
+		// An image source without digest matches the source image regardless of its digest.
+		candidates = append(candidates, idx.images[strings.TrimSuffix(sourceImage, "@"+digest)]...)
+	}
+
+	var matches [][]int
+	for node, i := idx.prefixes, 0; node != nil; i++ {
+		if len(node.overwrites) > 0 {
+			matches = append(matches, node.overwrites)
+		}
+		if i == len(sourceImage) {
+			break
+		}
+		node = node.children[sourceImage[i]]
+	}
+	// The longest prefix is matched first.
+	for i := len(matches) - 1; i >= 0; i-- {
+		candidates = append(candidates, matches[i]...)
+	}
+
+	return append(candidates, idx.patterns...)
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultDomain is the registry domain which is used for images without an explicit domain.
+	DefaultDomain = "docker.io"
+	// DefaultTag is the tag which is used for images without tag and digest.
+	DefaultTag = "latest"
+
+	legacyDefaultDomain = "index.docker.io"
+	officialRepoPrefix  = "library/"
+	localhost           = "localhost"
+)
+
+// The expressions follow the reference grammar of the distribution project, see
+// https://github.com/distribution/reference/blob/main/reference.go.
+const (
+	alphanumeric    = `[a-z0-9]+`
+	separator       = `(?:[._]|__|[-]+)`
+	pathComponent   = alphanumeric + `(?:` + separator + alphanumeric + `)*`
+	domainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	ipv6Address     = `\[(?:[a-fA-F0-9:]+)\]`
+	domainName      = domainComponent + `(?:\.` + domainComponent + `)*`
+	domainAndPort   = `(?:` + domainName + `|` + ipv6Address + `)(?::[0-9]+)?`
+	tag             = `[\w][\w.-]{0,127}`
+	digest          = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*[:][[:xdigit:]]{32,}`
+	remoteName      = pathComponent + `(?:/` + pathComponent + `)*`
+	namePattern     = `(?:` + domainAndPort + `/)?` + remoteName
+)
+
+var referencePattern = regexp.MustCompile(`^(` + namePattern + `)(?::(` + tag + `))?(?:@(` + digest + `))?$`)
+
+// Reference is a normalised image reference.
+type Reference struct {
+	// Domain is the registry domain of the image, e.g. 'docker.io'.
+	Domain string
+	// Path is the repository path of the image, e.g. 'library/nginx'.
+	Path string
+	// Tag is the tag of the image. It is empty if the image is only referenced by digest.
+	Tag string
+	// Digest is the digest of the image, if any.
+	Digest string
+}
+
+// Name returns the fully qualified repository name of the reference.
+func (r Reference) Name() string {
+	return r.Domain + "/" + r.Path
+}
+
+// String returns the canonical form of the reference.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// ParseNormalizedReference parses the given image and normalises it, i.e. images without domain refer to 'docker.io',
+// official images on 'docker.io' are prefixed with 'library/' and images without tag and digest get the 'latest' tag.
+func ParseNormalizedReference(image string) (Reference, error) {
+	name, tag, digest, err := splitReference(image)
+	if err != nil {
+		return Reference{}, err
+	}
+
+	domain, path := splitDomain(name)
+	if tag == "" && digest == "" {
+		tag = DefaultTag
+	}
+
+	return Reference{Domain: domain, Path: path, Tag: tag, Digest: digest}, nil
+}
+
+// NormalizePrefix normalises an image prefix the same way ParseNormalizedReference normalises images. Only a single
+// path segment without domain is the name of an official image, e.g. 'nginx' becomes 'docker.io/library/nginx'.
+// Prefixes with a domain keep their path, e.g. 'index.docker.io/bitnami' becomes 'docker.io/bitnami', so that they
+// still match all images of the organisation.
+func NormalizePrefix(prefix string) string {
+	first, remainder, hasPath := strings.Cut(prefix, "/")
+	switch {
+	case isDomain(first) && !hasPath:
+		return normalizeDomain(first)
+	case isDomain(first):
+		return normalizeDomain(first) + "/" + remainder
+	case !hasPath:
+		return DefaultDomain + "/" + officialRepoPrefix + prefix
+	default:
+		return DefaultDomain + "/" + prefix
+	}
+}
+
+func splitReference(image string) (string, string, string, error) {
+	matches := referencePattern.FindStringSubmatch(image)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("invalid image reference %q", image)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+func splitDomain(name string) (string, string) {
+	domain, path, hasPath := strings.Cut(name, "/")
+	if !hasPath || !isDomain(domain) {
+		domain, path = DefaultDomain, name
+	}
+
+	domain = normalizeDomain(domain)
+	if domain == DefaultDomain && !strings.ContainsRune(path, '/') {
+		path = officialRepoPrefix + path
+	}
+
+	return domain, path
+}
+
+func isDomain(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == localhost || strings.ToLower(s) != s
+}
+
+func normalizeDomain(domain string) string {
+	if domain == legacyDefaultDomain {
+		return DefaultDomain
+	}
+	return domain
+}
+
+// keepTagAndDigest adds tag and digest of the original reference to the target image if the target specifies neither
+// a tag nor a digest. If keepDigest is set, the digest is also added to targets which only specify a tag, i.e. an image
+// which is pinned by digest stays pinned if the source of the overwrite doesn't specify the digest.
+func keepTagAndDigest(target string, original Reference, keepDigest bool) string {
+	_, tag, digest, err := splitReference(target)
+	if err != nil || digest != "" || tag != "" && !keepDigest {
+		return target
+	}
+
+	if tag == "" && original.Tag != "" {
+		target += ":" + original.Tag
+	}
+	if original.Digest != "" {
+		target += "@" + original.Digest
+	}
+	return target
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package image_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+)
+
+var _ = Describe("Reference", func() {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	DescribeTable("#ParseNormalizedReference",
+		func(image, expected string) {
+			reference, err := ParseNormalizedReference(image)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reference.String()).To(Equal(expected))
+		},
+
+		Entry("official image", "nginx", "docker.io/library/nginx:latest"),
+		Entry("official image with domain", "docker.io/library/nginx", "docker.io/library/nginx:latest"),
+		Entry("official image with legacy domain", "index.docker.io/library/nginx:latest", "docker.io/library/nginx:latest"),
+		Entry("official image without library", "docker.io/nginx:1.27", "docker.io/library/nginx:1.27"),
+		Entry("user image", "gardener/image:v1", "docker.io/gardener/image:v1"),
+		Entry("image with digest", "nginx@"+digest, "docker.io/library/nginx@"+digest),
+		Entry("image with tag and digest", "nginx:1.27@"+digest, "docker.io/library/nginx:1.27@"+digest),
+		Entry("image with registry", "registry.k8s.io/pause:3.10", "registry.k8s.io/pause:3.10"),
+		Entry("image with registry and port", "localhost:5000/foo", "localhost:5000/foo:latest"),
+		Entry("image with localhost", "localhost/foo", "localhost/foo:latest"),
+	)
+
+	It("should fail to parse invalid references", func() {
+		_, err := ParseNormalizedReference("Foo/Bar")
+		Expect(err).To(HaveOccurred())
+
+		_, err = ParseNormalizedReference("nginx:")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("#NormalizePrefix",
+		func(prefix, expected string) {
+			Expect(NormalizePrefix(prefix)).To(Equal(expected))
+		},
+
+		Entry("registry", "registry.example.com", "registry.example.com"),
+		Entry("registry with path", "registry.example.com/foo/", "registry.example.com/foo/"),
+		Entry("legacy docker domain", "index.docker.io/library/", "docker.io/library/"),
+		Entry("docker user", "gardener/", "docker.io/gardener/"),
+		Entry("official image", "nginx", "docker.io/library/nginx"),
+		Entry("docker organisation", "docker.io/bitnami", "docker.io/bitnami"),
+		Entry("docker organisation with legacy domain", "index.docker.io/bitnami", "docker.io/bitnami"),
+		Entry("docker organisation with trailing slash", "docker.io/bitnami/", "docker.io/bitnami/"),
+		Entry("official image with domain", "docker.io/library/nginx", "docker.io/library/nginx"),
+		Entry("docker registry", "docker.io/", "docker.io/"),
+		Entry("official images", "docker.io/library", "docker.io/library"),
+		Entry("official images with legacy domain", "index.docker.io/library", "docker.io/library"),
+	)
+})
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package selector
+
+import (
+	"path"
+	"strings"
+)
+
+// Selector selects values which are configured per provider and regions. Providers and regions can be glob patterns,
+// see path.Match. The zero value is an empty selector.
+//
+// A value is selected from the configuration of the exact provider first and from the matching provider patterns in
+// the order they were added afterwards. Per provider, a value for the exact region takes precedence over a value for
+// a matching region pattern, the first matching pattern wins, which takes precedence over a value for any region.
+type Selector[T any] struct {
+	providers []provider[T]
+}
+
+type provider[T any] struct {
+	name     string
+	pattern  bool
+	regions  map[string]T
+	patterns []regionPattern[T]
+	global   *T
+}
+
+type regionPattern[T any] struct {
+	pattern string
+	value   T
+}
+
+// IsPattern returns true if the given provider or region is a glob pattern.
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, `*?[\`)
+}
+
+// ValidatePattern returns an error if the given glob pattern is malformed.
+func ValidatePattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// Add adds the value for the given provider and regions, no regions stand for any region. A value for the same
+// provider and region replaces the previous one, a value for any region doesn't replace the values for specific
+// regions.
+func (s *Selector[T]) Add(providerName string, regions []string, value T) {
+	p := s.provider(providerName)
+
+	for _, region := range regions {
+		if !IsPattern(region) {
+			p.regions[region] = value
+			continue
+		}
+
+		if i := indexOfPattern(p.patterns, region); i >= 0 {
+			p.patterns[i].value = value
+		} else {
+			p.patterns = append(p.patterns, regionPattern[T]{pattern: region, value: value})
+		}
+	}
+
+	if len(regions) == 0 {
+		p.global = &value
+	}
+}
+
+// Select returns the value for the given provider and region and whether there is one.
+func (s *Selector[T]) Select(providerName, region string) (T, bool) {
+	for i := range s.providers {
+		if p := &s.providers[i]; !p.pattern && p.name == providerName {
+			if value, ok := p.selectRegion(region); ok {
+				return value, true
+			}
+			break
+		}
+	}
+
+	for i := range s.providers {
+		if p := &s.providers[i]; p.pattern && match(p.name, providerName) {
+			if value, ok := p.selectRegion(region); ok {
+				return value, true
+			}
+		}
+	}
+
+	var empty T
+	return empty, false
+}
+
+func (s *Selector[T]) provider(name string) *provider[T] {
+	for i := range s.providers {
+		if s.providers[i].name == name {
+			return &s.providers[i]
+		}
+	}
+
+	s.providers = append(s.providers, provider[T]{
+		name:    name,
+		pattern: IsPattern(name),
+		regions: map[string]T{},
+	})
+	return &s.providers[len(s.providers)-1]
+}
+
+func (p *provider[T]) selectRegion(region string) (T, bool) {
+	if value, ok := p.regions[region]; ok {
+		return value, true
+	}
+	for _, regionPattern := range p.patterns {
+		if match(regionPattern.pattern, region) {
+			return regionPattern.value, true
+		}
+	}
+	if p.global != nil {
+		return *p.global, true
+	}
+
+	var empty T
+	return empty, false
+}
+
+func indexOfPattern[T any](patterns []regionPattern[T], pattern string) int {
+	for i, regionPattern := range patterns {
+		if regionPattern.pattern == pattern {
+			return i
+		}
+	}
+	return -1
+}
+
+func match(pattern, name string) bool {
+	// The configuration is validated beforehand, a malformed pattern doesn't match any name.
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
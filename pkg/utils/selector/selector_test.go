@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: SAP SE or an SAP affiliate company and Gardener contributors
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package selector_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/selector"
+)
+
+var _ = Describe("Selector", func() {
+	var s *Selector[string]
+
+	BeforeEach(func() {
+		s = &Selector[string]{}
+	})
+
+	selectValue := func(provider, region string) string {
+		value, ok := s.Select(provider, region)
+		Expect(value != "").To(Equal(ok))
+		return value
+	}
+
+	Describe("#Select", func() {
+		It("should return an empty value for an empty selector", func() {
+			Expect(selectValue("aws", "eu-west-1")).To(BeEmpty())
+		})
+
+		It("should prefer the exact region over a region pattern over any region", func() {
+			s.Add("aws", nil, "global")
+			s.Add("aws", []string{"eu-*"}, "europe")
+			s.Add("aws", []string{"eu-west-1"}, "ireland")
+
+			Expect(selectValue("aws", "eu-west-1")).To(Equal("ireland"))
+			Expect(selectValue("aws", "eu-central-1")).To(Equal("europe"))
+			Expect(selectValue("aws", "us-east-1")).To(Equal("global"))
+			Expect(selectValue("gcp", "eu-west-1")).To(BeEmpty())
+		})
+
+		It("should use the first matching region pattern", func() {
+			s.Add("aws", []string{"eu-*"}, "europe")
+			s.Add("aws", []string{"eu-west-?"}, "west")
+
+			Expect(selectValue("aws", "eu-west-1")).To(Equal("europe"))
+		})
+
+		It("should replace the value of the same region pattern", func() {
+			s.Add("aws", []string{"eu-*", "us-*"}, "first")
+			s.Add("aws", []string{"eu-*"}, "second")
+
+			Expect(selectValue("aws", "eu-west-1")).To(Equal("second"))
+			Expect(selectValue("aws", "us-east-1")).To(Equal("first"))
+		})
+
+		It("should prefer the exact provider over a provider pattern", func() {
+			s.Add("aws*", []string{"eu-*"}, "aws-europe")
+			s.Add("*", nil, "any")
+			s.Add("aws", []string{"us-east-1"}, "aws-virginia")
+
+			Expect(selectValue("aws", "us-east-1")).To(Equal("aws-virginia"))
+			Expect(selectValue("aws", "eu-west-1")).To(Equal("aws-europe"))
+			Expect(selectValue("aws-china", "us-east-1")).To(Equal("any"))
+			Expect(selectValue("gcp", "europe-west1")).To(Equal("any"))
+		})
+
+		It("should use the first matching provider pattern with a value for the region", func() {
+			s.Add("*", []string{"us-*"}, "any-us")
+			s.Add("aws*", nil, "aws")
+
+			Expect(selectValue("aws", "us-east-1")).To(Equal("any-us"))
+			Expect(selectValue("aws", "eu-west-1")).To(Equal("aws"))
+		})
+
+		It("should not match malformed patterns", func() {
+			s.Add("aws", []string{"eu-["}, "europe")
+
+			Expect(selectValue("aws", "eu-[")).To(BeEmpty())
+		})
+	})
+
+	Describe("#IsPattern", func() {
+		It("should detect glob patterns", func() {
+			Expect(IsPattern("eu-west-1")).To(BeFalse())
+			Expect(IsPattern("eu-*")).To(BeTrue())
+			Expect(IsPattern("eu-west-?")).To(BeTrue())
+			Expect(IsPattern("eu-[ab]")).To(BeTrue())
+		})
+	})
+
+	Describe("#ValidatePattern", func() {
+		It("should reject malformed patterns", func() {
+			Expect(ValidatePattern("eu-*")).To(Succeed())
+			Expect(ValidatePattern("eu-[")).To(HaveOccurred())
+		})
+	})
+})
@@ -7,26 +7,36 @@ package containerd
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"slices"
+	"strings"
+	"time"
 
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/containerd"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/event"
 )
 
 type mutator struct {
-	client client.Client
-	config containerd.Configuration
+	client   client.Client
+	recorder events.EventRecorder
+	config   *configutils.Store
 }
 
-func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
+func (m *mutator) Mutate(ctx context.Context, new, old client.Object) error {
+	defer metrics.ObserveMutateDuration(Name, time.Now())
+
 	log := logf.FromContext(ctx)
 
 	cluster, err := extensionscontroller.GetCluster(ctx, m.client, new.GetNamespace())
@@ -43,55 +53,113 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 		return nil
 	}
 
+	global := m.config.Get()
+	config, err := m.config.ForNamespace(ctx, log, m.client, new.GetNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to get image rewriter configuration: %w", err)
+	}
+
 	var (
-		shootProvider = cluster.Shoot.Spec.Provider.Type
-		shootRegion   = cluster.Shoot.Spec.Region
+		containerdConfig = containerd.NewConfiguration(config)
+		shootProvider    = cluster.Shoot.Spec.Provider.Type
+		shootRegion      = cluster.Shoot.Spec.Region
+		appliedUpstreams []string
+		// conflicts are the notes of the warning events about conflicting registry configurations.
+		conflicts []string
+		// paths are the paths of the files which are added or updated.
+		paths  []string
+		before = osc.DeepCopy()
 	)
 
 	switch osc.Spec.Purpose {
 	case extensionsv1alpha1.OperatingSystemConfigPurposeReconcile:
-		for _, upstreamConfig := range m.config.GetUpstreamConfig(shootProvider, shootRegion) {
+		for _, upstreamConfig := range containerdConfig.GetUpstreamConfig(shootProvider, shootRegion) {
 			if osc.Spec.CRIConfig.Containerd == nil {
 				osc.Spec.CRIConfig.Containerd = &extensionsv1alpha1.ContainerdConfig{}
 			}
 
-			// Don't overwrite existing upstream configuration to not collide with other extensions (e.g. registry-cache)
-			if hasUpstreamConfiguration(osc.Spec.CRIConfig.Containerd, upstreamConfig.Upstream) {
-				continue
-			}
+			registryConfig, files := newRegistryConfig(log, upstreamConfig)
 
-			log.V(2).Info("Adding registry mirror configuration for node reconciliation", "upstream", upstreamConfig.Upstream)
+			// Existing upstream configurations of other extensions (e.g. registry-cache) are merged according to the merge
+			// strategy of the upstream, they are left unchanged by default. Only configurations which exist when the webhook
+			// is invoked are merged, the order of the webhooks of different extensions is not defined.
+			registries := osc.Spec.CRIConfig.Containerd.Registries
+			if i := slices.IndexFunc(registries, func(registry extensionsv1alpha1.RegistryConfig) bool {
+				return registry.Upstream == upstreamConfig.Upstream
+			}); i >= 0 {
+				mergeStrategy := upstreamConfig.MergeStrategy
+				skip := mergeStrategy == "" || mergeStrategy == v1alpha1.MergeStrategySkip
+				merged := mergeRegistryConfig(registries[i], registryConfig, mergeStrategy)
 
-			osc.Spec.CRIConfig.Containerd.Registries = append(osc.Spec.CRIConfig.Containerd.Registries, extensionsv1alpha1.RegistryConfig{
-				Upstream: upstreamConfig.Upstream,
-				Server:   ptr.To(upstreamConfig.Server),
-				Hosts: []extensionsv1alpha1.RegistryHost{
-					{
-						URL:          upstreamConfig.HostURL,
-						Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability},
-						OverridePath: upstreamConfig.OverridePath,
-					},
-				},
-			})
+				// The registry configuration was added or merged by an earlier invocation of the webhook for the same
+				// request, e.g. if it is reinvoked after another webhook mutated the OperatingSystemConfig. It is neither
+				// merged nor counted again. Only an exact match is skipped, the existing configuration of another extension
+				// may contain the hosts already but still differ from the merged one, e.g. in the server. With the skip
+				// strategy, only a registry configuration which equals the one of the upstream was added by the webhook.
+				applied := merged
+				if skip {
+					applied = registryConfig
+				}
+				if apiequality.Semantic.DeepEqual(registries[i], applied) {
+					log.V(2).Info("Registry configuration already contains the registry mirrors", "upstream", upstreamConfig.Upstream)
+					appliedUpstreams = append(appliedUpstreams, upstreamConfig.Upstream)
+					for _, file := range files {
+						osc.Spec.Files = ensureCertificateFile(osc.Spec.Files, file)
+						paths = append(paths, file.Path)
+					}
+					continue
+				}
+
+				if skip {
+					metrics.ImagesUnchanged.WithLabelValues(Name, shootProvider, shootRegion, metrics.UpstreamLabel(global, upstreamConfig.Upstream)).Inc()
+					continue
+				}
+
+				if existingServer := registries[i].Server; existingServer != nil && registryConfig.Server != nil && *existingServer != *registryConfig.Server {
+					resolution := "the existing server is kept"
+					if mergeStrategy == v1alpha1.MergeStrategyReplace {
+						resolution = "the existing server is replaced"
+					}
+					log.Info("Existing registry configuration has a different server", "upstream", upstreamConfig.Upstream, "existingServer", *existingServer, "server", *registryConfig.Server, "mergeStrategy", mergeStrategy)
+					conflicts = append(conflicts, fmt.Sprintf("Existing registry configuration for upstream %s in %s OperatingSystemConfig %s has server %q instead of %q, %s with merge strategy %s",
+						upstreamConfig.Upstream, osc.Spec.Purpose, osc.Name, *existingServer, *registryConfig.Server, resolution, mergeStrategy))
+				}
+
+				log.V(2).Info("Merging registry mirror configuration for node reconciliation", "upstream", upstreamConfig.Upstream, "mergeStrategy", mergeStrategy)
+				registries[i] = merged
+			} else {
+				log.V(2).Info("Adding registry mirror configuration for node reconciliation", "upstream", upstreamConfig.Upstream)
+				osc.Spec.CRIConfig.Containerd.Registries = append(registries, registryConfig)
+			}
+
+			metrics.ImagesRewritten.WithLabelValues(Name, shootProvider, shootRegion, metrics.UpstreamLabel(global, upstreamConfig.Upstream)).Inc()
+			appliedUpstreams = append(appliedUpstreams, upstreamConfig.Upstream)
+			for _, file := range files {
+				osc.Spec.Files = ensureCertificateFile(osc.Spec.Files, file)
+				paths = append(paths, file.Path)
+			}
 		}
 
 	case extensionsv1alpha1.OperatingSystemConfigPurposeProvision:
-		for _, upstreamConfig := range m.config.GetUpstreamConfig(shootProvider, shootRegion) {
+		for _, upstreamConfig := range containerdConfig.GetUpstreamConfig(shootProvider, shootRegion) {
 			mirror := containerd.RegistryMirror{
+				Upstream:       upstreamConfig.Upstream,
 				UpstreamServer: upstreamConfig.Server,
-				MirrorHost:     upstreamConfig.HostURL,
-				OverridePath:   upstreamConfig.OverridePath,
+				MirrorHosts:    upstreamConfig.Hosts,
 			}
 
 			log.V(2).Info("Adding registry mirror configuration for node provisioning", "upstream", upstreamConfig.Upstream)
+			metrics.ImagesRewritten.WithLabelValues(Name, shootProvider, shootRegion, metrics.UpstreamLabel(global, upstreamConfig.Upstream)).Inc()
 
 			data, err := mirror.HostsTOML()
 			if err != nil {
 				return fmt.Errorf("failed to create hosts.toml file for upstream %q: %w", upstreamConfig.Upstream, err)
 			}
+			appliedUpstreams = append(appliedUpstreams, upstreamConfig.Upstream)
+			paths = append(paths, containerd.HostsTOMLPath(upstreamConfig.Upstream))
 
 			osc.Spec.Files = extensionswebhook.EnsureFileWithPath(osc.Spec.Files, extensionsv1alpha1.File{
-				Path:        filepath.Join("/etc/containerd/certs.d", upstreamConfig.Upstream, "hosts.toml"),
+				Path:        containerd.HostsTOMLPath(upstreamConfig.Upstream),
 				Permissions: ptr.To[uint32](0644),
 				Content: extensionsv1alpha1.FileContent{
 					Inline: &extensionsv1alpha1.FileContentInline{
@@ -99,26 +167,154 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 					},
 				},
 			})
+			for _, file := range mirror.Files() {
+				osc.Spec.Files = ensureCertificateFile(osc.Spec.Files, file)
+				paths = append(paths, file.Path)
+			}
 		}
 	}
 
+	// The OperatingSystemConfig is updated without registry mirrors on every reconciliation of the shoot and the webhook
+	// may be invoked again for the same request. Events are only recorded if the registry mirrors differ from the ones
+	// of the existing object and were not applied by an earlier invocation.
+	oldOSC, _ := old.(*extensionsv1alpha1.OperatingSystemConfig)
+	if apiequality.Semantic.DeepEqual(before.Spec, osc.Spec) || (oldOSC != nil && !registryMirrorsChanged(oldOSC, osc, paths)) {
+		return nil
+	}
+
+	for _, conflict := range conflicts {
+		event.RecordWarningForNamespace(ctx, log, m.client, m.recorder, osc.Namespace, event.ReasonRegistryConfigurationConflict, event.ActionMutate, "%s", conflict)
+	}
+	if len(appliedUpstreams) > 0 {
+		event.RecordForNamespace(ctx, log, m.client, m.recorder, osc.Namespace, event.ReasonRegistryMirrorsApplied, event.ActionMutate,
+			"Applied registry mirrors to %s OperatingSystemConfig %s for upstreams: %s", osc.Spec.Purpose, osc.Name, strings.Join(appliedUpstreams, ", "))
+	}
+
 	return nil
 }
 
-func hasUpstreamConfiguration(containerdConfig *extensionsv1alpha1.ContainerdConfig, upstream string) bool {
-	if containerdConfig == nil || containerdConfig.Registries == nil {
-		return false
+// registryMirrorsChanged returns true if the registry configurations or the files of the given paths differ.
+func registryMirrorsChanged(old, new *extensionsv1alpha1.OperatingSystemConfig, paths []string) bool {
+	if !apiequality.Semantic.DeepEqual(registries(old), registries(new)) {
+		return true
+	}
+	for _, path := range paths {
+		if !apiequality.Semantic.DeepEqual(fileWithPath(old.Spec.Files, path), fileWithPath(new.Spec.Files, path)) {
+			return true
+		}
 	}
+	return false
+}
+
+func fileWithPath(files []extensionsv1alpha1.File, path string) *extensionsv1alpha1.File {
+	if i := slices.IndexFunc(files, func(file extensionsv1alpha1.File) bool { return file.Path == path }); i >= 0 {
+		return &files[i]
+	}
+	return nil
+}
+
+func registries(osc *extensionsv1alpha1.OperatingSystemConfig) []extensionsv1alpha1.RegistryConfig {
+	if osc.Spec.CRIConfig == nil || osc.Spec.CRIConfig.Containerd == nil {
+		return nil
+	}
+	return osc.Spec.CRIConfig.Containerd.Registries
+}
+
+// newRegistryConfig returns the registry configuration of the upstream for node reconciliation and the files with the
+// PEM material it references.
+func newRegistryConfig(log logr.Logger, upstreamConfig containerd.UpStreamConfiguration) (extensionsv1alpha1.RegistryConfig, []containerd.CertificateFile) {
+	var (
+		registryConfig = extensionsv1alpha1.RegistryConfig{Upstream: upstreamConfig.Upstream}
+		files          []containerd.CertificateFile
+	)
+
+	// The default upstream has no server, containerd falls back to the registry of the pulled image.
+	if upstreamConfig.Server != "" {
+		registryConfig.Server = ptr.To(upstreamConfig.Server)
+	}
+
+	for _, host := range upstreamConfig.Hosts {
+		registryHost := extensionsv1alpha1.RegistryHost{
+			URL:          host.URL,
+			OverridePath: host.OverridePath,
+		}
+		for _, capability := range host.EffectiveCapabilities() {
+			registryHost.Capabilities = append(registryHost.Capabilities, extensionsv1alpha1.RegistryCapability(capability))
+		}
+
+		// The registry configuration only supports CA bundles, client certificates, skip_verify and headers are only
+		// applied via the hosts.toml files of provisioned nodes.
+		if unsupported := unsupportedForReconciliation(host); len(unsupported) > 0 {
+			log.Info("Host settings are not supported for node reconciliation, skipping them", "upstream", upstreamConfig.Upstream, "host", host.URL, "settings", unsupported)
+		}
+		if caPath := host.TLS.CAPath(upstreamConfig.Upstream); caPath != "" {
+			registryHost.CACerts = []string{caPath}
+			files = append(files, containerd.CertificateFile{Path: caPath, Content: host.TLS.CA, Permissions: 0644})
+		}
+
+		registryConfig.Hosts = append(registryConfig.Hosts, registryHost)
+	}
+
+	return registryConfig, files
+}
+
+// mergeRegistryConfig merges the registry configuration of the upstream into the existing one of another extension.
+// The existing configuration is returned unchanged with the skip strategy.
+func mergeRegistryConfig(existing, registryConfig extensionsv1alpha1.RegistryConfig, mergeStrategy v1alpha1.MergeStrategy) extensionsv1alpha1.RegistryConfig {
+	switch mergeStrategy {
+	case v1alpha1.MergeStrategyReplace:
+		return registryConfig
+	case v1alpha1.MergeStrategyPrepend:
+		existing.Hosts = mergeHosts(registryConfig.Hosts, existing.Hosts)
+	case v1alpha1.MergeStrategyAppend:
+		existing.Hosts = mergeHosts(existing.Hosts, registryConfig.Hosts)
+	}
+	return existing
+}
+
+// mergeHosts returns the first hosts followed by the second hosts whose URL is not contained in the first hosts.
+func mergeHosts(first, second []extensionsv1alpha1.RegistryHost) []extensionsv1alpha1.RegistryHost {
+	hosts := slices.Clone(first)
+	for _, host := range second {
+		if !slices.ContainsFunc(first, func(h extensionsv1alpha1.RegistryHost) bool { return h.URL == host.URL }) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// unsupportedForReconciliation returns the settings of the host which the registry configuration doesn't support.
+func unsupportedForReconciliation(host containerd.Host) []string {
+	var unsupported []string
+	if host.TLS != nil && host.TLS.ClientCert != "" {
+		unsupported = append(unsupported, "client")
+	}
+	if host.TLS != nil && host.TLS.SkipVerify {
+		unsupported = append(unsupported, "skipVerify")
+	}
+	if len(host.Header) > 0 {
+		unsupported = append(unsupported, "header")
+	}
+	return unsupported
+}
 
-	return slices.ContainsFunc(containerdConfig.Registries, func(registry extensionsv1alpha1.RegistryConfig) bool {
-		return registry.Upstream == upstream
+func ensureCertificateFile(files []extensionsv1alpha1.File, file containerd.CertificateFile) []extensionsv1alpha1.File {
+	return extensionswebhook.EnsureFileWithPath(files, extensionsv1alpha1.File{
+		Path:        file.Path,
+		Permissions: ptr.To(file.Permissions),
+		Content: extensionsv1alpha1.FileContent{
+			Inline: &extensionsv1alpha1.FileContentInline{
+				Data: file.Content,
+			},
+		},
 	})
 }
 
 // NewMutator creates a new Mutator instance.
-func NewMutator(client client.Client, config *v1alpha1.Configuration) extensionswebhook.Mutator {
+func NewMutator(client client.Client, recorder events.EventRecorder, config *configutils.Store) extensionswebhook.Mutator {
 	return &mutator{
-		client: client,
-		config: containerd.NewConfiguration(config),
+		client:   client,
+		recorder: recorder,
+		config:   config,
 	}
 }
@@ -11,13 +11,17 @@ import (
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	. "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/containerd"
 )
 
@@ -26,8 +30,9 @@ var _ = Describe("Mutator", func() {
 		ctx        context.Context
 		fakeClient client.Client
 
-		config  *v1alpha1.Configuration
-		mutator extensionswebhook.Mutator
+		recorder *events.FakeRecorder
+		config   *v1alpha1.Configuration
+		mutator  extensionswebhook.Mutator
 
 		namespace string
 		cluster   *extensionsv1alpha1.Cluster
@@ -73,7 +78,8 @@ var _ = Describe("Mutator", func() {
 			},
 		}
 
-		mutator = NewMutator(fakeClient, config)
+		recorder = events.NewFakeRecorder(10)
+		mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
 
 		namespace = "shoot--test--local"
 
@@ -150,6 +156,104 @@ var _ = Describe("Mutator", func() {
 						},
 					},
 				))
+				Expect(recorder.Events).To(Receive(Equal("Normal RegistryMirrorsApplied Applied registry mirrors to provision OperatingSystemConfig test-osc for upstreams: upstream1, upstream2")))
+			})
+
+			It("should add all hosts in the configured order", func() {
+				config.Containerd[0].Hosts[1] = v1alpha1.ContainerdHostConfig{
+					URLs:     []string{"https://mirror1-central", "https://mirror1/global"},
+					Provider: "local",
+					Regions:  []string{"central", "south", "north"},
+				}
+				config.Containerd = config.Containerd[:1]
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.Files).To(ConsistOf(
+					extensionsv1alpha1.File{
+						Path:        "/etc/containerd/certs.d/upstream1/hosts.toml",
+						Permissions: ptr.To[uint32](0644),
+						Content: extensionsv1alpha1.FileContent{
+							Inline: &extensionsv1alpha1.FileContentInline{
+								Data: `server = "https://server1"
+
+[host."https://mirror1-central"]
+  capabilities = ["pull", "resolve"]
+
+[host."https://mirror1/global"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+`,
+							},
+						},
+					},
+				))
+			})
+
+			It("should add the hosts.toml file of the default upstream", func() {
+				config.Containerd = append(config.Containerd[:1], v1alpha1.ContainerdConfiguration{
+					Upstream: "_default",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://cache.example.com", Provider: "local"}},
+				})
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.Files).To(ContainElement(extensionsv1alpha1.File{
+					Path:        "/etc/containerd/certs.d/_default/hosts.toml",
+					Permissions: ptr.To[uint32](0644),
+					Content: extensionsv1alpha1.FileContent{
+						Inline: &extensionsv1alpha1.FileContentInline{
+							Data: `[host."https://cache.example.com"]
+  capabilities = ["pull", "resolve"]
+`,
+						},
+					},
+				}))
+				Expect(osc.Spec.Files).To(HaveLen(2))
+			})
+
+			It("should add the PEM files of the hosts to the OperatingSystemConfig", func() {
+				config.Containerd[0].Hosts[1].CA = "ca"
+				config.Containerd[0].Hosts[1].Client = &v1alpha1.ContainerdClientCertificate{Cert: "cert", Key: "key"}
+				config.Containerd = config.Containerd[:1]
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.Files).To(ConsistOf(
+					extensionsv1alpha1.File{
+						Path:        "/etc/containerd/certs.d/upstream1/hosts.toml",
+						Permissions: ptr.To[uint32](0644),
+						Content: extensionsv1alpha1.FileContent{
+							Inline: &extensionsv1alpha1.FileContentInline{
+								Data: `server = "https://server1"
+
+[host."https://mirror1-central"]
+  capabilities = ["pull", "resolve"]
+  ca = "/etc/containerd/certs.d/upstream1/ca-6959097001d10501.crt"
+  client = [["/etc/containerd/certs.d/upstream1/client-44b56c2f52d28dfd.cert", "/etc/containerd/certs.d/upstream1/client-44b56c2f52d28dfd.key"]]
+`,
+							},
+						},
+					},
+					extensionsv1alpha1.File{
+						Path:        "/etc/containerd/certs.d/upstream1/ca-6959097001d10501.crt",
+						Permissions: ptr.To[uint32](0644),
+						Content:     extensionsv1alpha1.FileContent{Inline: &extensionsv1alpha1.FileContentInline{Data: "ca"}},
+					},
+					extensionsv1alpha1.File{
+						Path:        "/etc/containerd/certs.d/upstream1/client-44b56c2f52d28dfd.cert",
+						Permissions: ptr.To[uint32](0644),
+						Content:     extensionsv1alpha1.FileContent{Inline: &extensionsv1alpha1.FileContentInline{Data: "cert"}},
+					},
+					extensionsv1alpha1.File{
+						Path:        "/etc/containerd/certs.d/upstream1/client-44b56c2f52d28dfd.key",
+						Permissions: ptr.To[uint32](0600),
+						Content:     extensionsv1alpha1.FileContent{Inline: &extensionsv1alpha1.FileContentInline{Data: "key"}},
+					},
+				))
 			})
 
 			It("should leave OperatingSystemConfig files unchanged when no configuration matches", func() {
@@ -177,6 +281,7 @@ var _ = Describe("Mutator", func() {
 				Expect(mutator.Mutate(ctx, oscCopy, nil)).To(Succeed())
 
 				Expect(oscCopy.Spec.Files).To(BeEmpty())
+				Expect(recorder.Events).NotTo(Receive())
 			})
 		})
 
@@ -206,6 +311,117 @@ var _ = Describe("Mutator", func() {
 				))
 			})
 
+			It("should only record an event if the registry mirrors changed", func() {
+				oldOSC := osc.DeepCopy()
+				Expect(mutator.Mutate(ctx, oldOSC, nil)).To(Succeed())
+				Expect(recorder.Events).To(Receive())
+
+				// The webhook is invoked again for the same request.
+				Expect(mutator.Mutate(ctx, oldOSC, nil)).To(Succeed())
+				Expect(recorder.Events).NotTo(Receive())
+
+				// The OperatingSystemConfig is updated without the registry mirrors which were applied before.
+				Expect(mutator.Mutate(ctx, osc, oldOSC)).To(Succeed())
+				Expect(osc.Spec.CRIConfig.Containerd.Registries).To(HaveLen(2))
+				Expect(recorder.Events).NotTo(Receive())
+			})
+
+			It("should add all hosts in the configured order", func() {
+				config.Containerd[0].Hosts[1] = v1alpha1.ContainerdHostConfig{
+					URLs:     []string{"https://mirror1-central", "https://mirror1/global"},
+					Provider: "local",
+					Regions:  []string{"central", "south", "north"},
+				}
+				config.Containerd = config.Containerd[:1]
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(
+					extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability}},
+							{URL: "https://mirror1/global", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability}, OverridePath: ptr.To(true)},
+						},
+					},
+				))
+			})
+
+			It("should add the CA bundle of the hosts to the OperatingSystemConfig", func() {
+				config.Containerd[0].Hosts[1].CA = "ca"
+				config.Containerd[0].Hosts[1].Client = &v1alpha1.ContainerdClientCertificate{Cert: "cert", Key: "key"}
+				config.Containerd = config.Containerd[:1]
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(
+					extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{
+								URL:          "https://mirror1-central",
+								Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability},
+								CACerts:      []string{"/etc/containerd/certs.d/upstream1/ca-6959097001d10501.crt"},
+							},
+						},
+					},
+				))
+				Expect(osc.Spec.Files).To(ConsistOf(extensionsv1alpha1.File{
+					Path:        "/etc/containerd/certs.d/upstream1/ca-6959097001d10501.crt",
+					Permissions: ptr.To[uint32](0644),
+					Content:     extensionsv1alpha1.FileContent{Inline: &extensionsv1alpha1.FileContentInline{Data: "ca"}},
+				}))
+			})
+
+			It("should add the registry configuration of the default upstream without server", func() {
+				config.Containerd = append(config.Containerd[:1], v1alpha1.ContainerdConfiguration{
+					Upstream: "_default",
+					Hosts:    []v1alpha1.ContainerdHostConfig{{URL: "https://cache.example.com", Provider: "local"}},
+				})
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(
+					extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability}},
+						},
+					},
+					extensionsv1alpha1.RegistryConfig{
+						Upstream: "_default",
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://cache.example.com", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability}},
+						},
+					},
+				))
+			})
+
+			It("should add the configured capabilities of the hosts", func() {
+				config.Containerd[0].Hosts[1].Capabilities = []v1alpha1.ContainerdCapability{v1alpha1.ContainerdCapabilityPull}
+				config.Containerd[0].Hosts[1].Header = map[string][]string{"X-Tenant": {"team-a"}}
+				config.Containerd = config.Containerd[:1]
+				mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(
+					extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability}},
+						},
+					},
+				))
+			})
+
 			It("should leave already configured upstream unchanged", func() {
 				osc.Spec.CRIConfig.Containerd = &extensionsv1alpha1.ContainerdConfig{
 					Registries: []extensionsv1alpha1.RegistryConfig{
@@ -237,6 +453,174 @@ var _ = Describe("Mutator", func() {
 						},
 					},
 				))
+				Expect(recorder.Events).To(Receive(Equal("Normal RegistryMirrorsApplied Applied registry mirrors to reconcile OperatingSystemConfig test-osc for upstreams: upstream2")))
+			})
+
+			Context("with existing upstream configuration", func() {
+				var (
+					capabilities = []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability, extensionsv1alpha1.ResolveCapability}
+					existingHost = extensionsv1alpha1.RegistryHost{URL: "https://custom-mirror4", Capabilities: capabilities}
+				)
+
+				BeforeEach(func() {
+					osc.Spec.CRIConfig.Containerd = &extensionsv1alpha1.ContainerdConfig{
+						Registries: []extensionsv1alpha1.RegistryConfig{
+							{
+								Upstream: "upstream1",
+								Server:   ptr.To("https://server1"),
+								Hosts:    []extensionsv1alpha1.RegistryHost{existingHost},
+							},
+						},
+					}
+					config.Containerd = config.Containerd[:1]
+				})
+
+				test := func(mergeStrategy v1alpha1.MergeStrategy, expected extensionsv1alpha1.RegistryConfig) {
+					GinkgoHelper()
+
+					config.Containerd[0].MergeStrategy = mergeStrategy
+					mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
+
+					Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+					Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(expected))
+				}
+
+				It("should append the hosts", func() {
+					test(v1alpha1.MergeStrategyAppend, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							existingHost,
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					})
+					Expect(recorder.Events).To(Receive(Equal("Normal RegistryMirrorsApplied Applied registry mirrors to reconcile OperatingSystemConfig test-osc for upstreams: upstream1")))
+				})
+
+				It("should prepend the hosts", func() {
+					test(v1alpha1.MergeStrategyPrepend, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+							existingHost,
+						},
+					})
+				})
+
+				It("should not add hosts which already exist", func() {
+					osc.Spec.CRIConfig.Containerd.Registries[0].Hosts = append(osc.Spec.CRIConfig.Containerd.Registries[0].Hosts,
+						extensionsv1alpha1.RegistryHost{URL: "https://mirror1-central", Capabilities: []extensionsv1alpha1.RegistryCapability{extensionsv1alpha1.PullCapability}})
+
+					test(v1alpha1.MergeStrategyPrepend, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+							existingHost,
+						},
+					})
+				})
+
+				It("should replace the existing configuration", func() {
+					test(v1alpha1.MergeStrategyReplace, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					})
+				})
+
+				It("should replace the existing configuration if it already contains the hosts", func() {
+					osc.Spec.CRIConfig.Containerd.Registries[0].Server = ptr.To("https://server4")
+					osc.Spec.CRIConfig.Containerd.Registries[0].Hosts = append(osc.Spec.CRIConfig.Containerd.Registries[0].Hosts,
+						extensionsv1alpha1.RegistryHost{URL: "https://mirror1-central", Capabilities: capabilities})
+					rewritten := testutil.ToFloat64(metrics.ImagesRewritten.WithLabelValues(Name, "local", "north", "global/0"))
+
+					test(v1alpha1.MergeStrategyReplace, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					})
+					Expect(recorder.Events).To(Receive(HavePrefix("Warning RegistryConfigurationConflict")))
+					Expect(recorder.Events).To(Receive(Equal("Normal RegistryMirrorsApplied Applied registry mirrors to reconcile OperatingSystemConfig test-osc for upstreams: upstream1")))
+					Expect(testutil.ToFloat64(metrics.ImagesRewritten.WithLabelValues(Name, "local", "north", "global/0"))).To(Equal(rewritten + 1))
+				})
+
+				It("should leave the existing configuration unchanged with the skip strategy", func() {
+					test(v1alpha1.MergeStrategySkip, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts:    []extensionsv1alpha1.RegistryHost{existingHost},
+					})
+					Expect(recorder.Events).NotTo(Receive())
+				})
+
+				It("should not merge or count the hosts again when the webhook is invoked again", func() {
+					osc.Spec.CRIConfig.Containerd.Registries[0].Server = ptr.To("https://server4")
+					expected := extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server4"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							existingHost,
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					}
+					rewritten := testutil.ToFloat64(metrics.ImagesRewritten.WithLabelValues(Name, "local", "north", "global/0"))
+
+					test(v1alpha1.MergeStrategyAppend, expected)
+					Expect(recorder.Events).To(Receive(HavePrefix("Warning RegistryConfigurationConflict")))
+					Expect(recorder.Events).To(Receive(HavePrefix("Normal RegistryMirrorsApplied")))
+
+					Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+					Expect(osc.Spec.CRIConfig.Containerd.Registries).To(ConsistOf(expected))
+					Expect(recorder.Events).NotTo(Receive())
+					Expect(testutil.ToFloat64(metrics.ImagesRewritten.WithLabelValues(Name, "local", "north", "global/0"))).To(Equal(rewritten + 1))
+				})
+
+				It("should not count its own hosts as unchanged with the skip strategy when the webhook is invoked again", func() {
+					osc.Spec.CRIConfig.Containerd.Registries = nil
+					unchanged := testutil.ToFloat64(metrics.ImagesUnchanged.WithLabelValues(Name, "local", "north", "global/0"))
+
+					test(v1alpha1.MergeStrategySkip, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts:    []extensionsv1alpha1.RegistryHost{{URL: "https://mirror1-central", Capabilities: capabilities}},
+					})
+					Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+					Expect(testutil.ToFloat64(metrics.ImagesUnchanged.WithLabelValues(Name, "local", "north", "global/0"))).To(Equal(unchanged))
+				})
+
+				It("should report a conflict if the existing server differs", func() {
+					osc.Spec.CRIConfig.Containerd.Registries[0].Server = ptr.To("https://server4")
+
+					test(v1alpha1.MergeStrategyAppend, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server4"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							existingHost,
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					})
+					Expect(recorder.Events).To(Receive(Equal(`Warning RegistryConfigurationConflict Existing registry configuration for upstream upstream1 in reconcile OperatingSystemConfig test-osc has server "https://server4" instead of "https://server1", the existing server is kept with merge strategy Append`)))
+					Expect(recorder.Events).To(Receive(Equal("Normal RegistryMirrorsApplied Applied registry mirrors to reconcile OperatingSystemConfig test-osc for upstreams: upstream1")))
+				})
+
+				It("should report a conflict if the replaced server differs", func() {
+					osc.Spec.CRIConfig.Containerd.Registries[0].Server = ptr.To("https://server4")
+
+					test(v1alpha1.MergeStrategyReplace, extensionsv1alpha1.RegistryConfig{
+						Upstream: "upstream1",
+						Server:   ptr.To("https://server1"),
+						Hosts: []extensionsv1alpha1.RegistryHost{
+							{URL: "https://mirror1-central", Capabilities: capabilities},
+						},
+					})
+					Expect(recorder.Events).To(Receive(Equal(`Warning RegistryConfigurationConflict Existing registry configuration for upstream upstream1 in reconcile OperatingSystemConfig test-osc has server "https://server4" instead of "https://server1", the existing server is replaced with merge strategy Replace`)))
+				})
 			})
 
 			It("should leave OperatingSystemConfig containerd unchanged when no configuration matches", func() {
@@ -264,6 +648,7 @@ var _ = Describe("Mutator", func() {
 				Expect(mutator.Mutate(ctx, oscCopy, nil)).To(Succeed())
 
 				Expect(oscCopy.Spec.CRIConfig.Containerd).To(BeNil())
+				Expect(recorder.Events).NotTo(Receive())
 			})
 		})
 	})
@@ -14,7 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 )
 
 const (
@@ -29,7 +29,7 @@ var (
 
 // AddOptions are options to apply when adding the AWS shoot webhook to the manager.
 type AddOptions struct {
-	Config v1alpha1.Configuration
+	Config *configutils.Store
 }
 
 // AddToManager creates a webhook and adds it to the manager.
@@ -42,7 +42,7 @@ func AddToManager(mgr manager.Manager) (*extensionswebhook.Webhook, error) {
 		{Obj: &extensionsv1alpha1.OperatingSystemConfig{}},
 	}
 
-	handler, err := extensionswebhook.NewBuilder(mgr, logger).WithMutator(NewMutator(mgr.GetClient(), &DefaultAddOptions.Config), types...).Build()
+	handler, err := extensionswebhook.NewBuilder(mgr, logger).WithMutator(NewMutator(mgr.GetClient(), mgr.GetEventRecorder(Name), DefaultAddOptions.Config), types...).Build()
 	if err != nil {
 		return nil, err
 	}
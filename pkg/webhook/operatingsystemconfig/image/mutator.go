@@ -7,23 +7,31 @@ package image
 import (
 	"context"
 	"fmt"
+	"maps"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	extensionsv1alpha1helper "github.com/gardener/gardener/pkg/api/extensions/v1alpha1/helper"
 	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	gardenerutils "github.com/gardener/gardener/pkg/utils"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/event"
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
 )
 
 type mutator struct {
-	client client.Client
-	config image.Configuration
+	client   client.Client
+	recorder events.EventRecorder
+	config   *configutils.Store
 }
 
 // Regex matches:
@@ -32,7 +40,9 @@ type mutator struct {
 // 3. prefix:version@sha256:checksum
 var ociImagePattern = regexp.MustCompile(`\b[\w\-\.\/]+:(?:[\w\.\-]+@sha256:[a-fA-F0-9]{64}|[\w\.\-]+)|[\w\-\.\/]+@sha256:[a-fA-F0-9]{64}\b`)
 
-func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
+func (m *mutator) Mutate(ctx context.Context, new, old client.Object) error {
+	defer metrics.ObserveMutateDuration(Name, time.Now())
+
 	log := logf.FromContext(ctx)
 
 	cluster, err := extensionscontroller.GetCluster(ctx, m.client, new.GetNamespace())
@@ -45,25 +55,59 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 		return fmt.Errorf("expected new object to be of type *extensionsv1alpha1.OperatingSystemConfig, got %T", new)
 	}
 
+	global := m.config.Get()
+	imageConfig, err := m.config.ImagesForNamespace(ctx, log, m.client, new.GetNamespace())
+	if err != nil {
+		return fmt.Errorf("failed to get image rewriter configuration: %w", err)
+	}
+
 	var (
 		shootProvider = cluster.Shoot.Spec.Provider.Type
 		shootRegion   = cluster.Shoot.Spec.Region
+		// originalImages maps the images rewritten by this mutation to the original images.
+		originalImages = map[string]string{}
+		// keptImages maps the images rewritten by an earlier mutation to the original images.
+		keptImages = map[string]string{}
 	)
 
+	// The annotation is rebuilt on every mutation. Entries of earlier mutations, e.g. of a reinvocation of the webhook,
+	// are kept as long as the image is still contained and is the image the original image is rewritten to.
+	recorded, err := image.GetOriginalImages(osc)
+	if err != nil {
+		log.Info("Ignoring original images annotation which cannot be decoded", "error", err.Error())
+	}
+
+	// findTargetImage returns the target image of the given source image. The source image is an image reference unless
+	// it was found in the content of a file, where misses are not counted since most matches are no images at all.
+	findTargetImage := func(sourceImage string, imageReference bool) string {
+		result := imageConfig.Lookup(sourceImage, shootProvider, shootRegion)
+		if imageReference || result.Rule != "" {
+			metrics.RecordLookup(Name, shootProvider, shootRegion, global, result)
+		}
+		if result.Target == "" {
+			if original, ok := recorded[sourceImage]; ok && imageConfig.Lookup(original, shootProvider, shootRegion).Target == sourceImage {
+				keptImages[sourceImage] = original
+			}
+		}
+		return result.Target
+	}
+
 	switch osc.Spec.Purpose {
 	case extensionsv1alpha1.OperatingSystemConfigPurposeReconcile:
 		for i, file := range osc.Spec.Files {
 			if file.Content.ImageRef != nil {
-				if newImage := m.config.FindTargetImage(file.Content.ImageRef.Image, shootProvider, shootRegion); newImage != "" {
+				if newImage := findTargetImage(file.Content.ImageRef.Image, true); newImage != "" {
 					log.V(2).Info("Replacing image in OperatingSystemConfig file", "oldImage", file.Content.ImageRef.Image, "newImage", newImage)
+					originalImages[newImage] = file.Content.ImageRef.Image
 					osc.Spec.Files[i].Content.ImageRef.Image = newImage
 				}
 			}
 		}
 
 		if extensionsv1alpha1helper.HasContainerdConfiguration(osc.Spec.CRIConfig) {
-			if newImage := m.config.FindTargetImage(osc.Spec.CRIConfig.Containerd.SandboxImage, shootProvider, shootRegion); newImage != "" {
+			if newImage := findTargetImage(osc.Spec.CRIConfig.Containerd.SandboxImage, true); newImage != "" {
 				log.V(2).Info("Replacing sandbox image in OperatingSystemConfig file", "oldImage", osc.Spec.CRIConfig.Containerd.SandboxImage, "newImage", newImage)
+				originalImages[newImage] = osc.Spec.CRIConfig.Containerd.SandboxImage
 				osc.Spec.CRIConfig.Containerd.SandboxImage = newImage
 			}
 		}
@@ -78,8 +122,9 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 
 				var updated bool
 				data = ociImagePattern.ReplaceAllStringFunc(data, func(match string) string {
-					if newImage := m.config.FindTargetImage(match, shootProvider, shootRegion); newImage != "" {
+					if newImage := findTargetImage(match, false); newImage != "" {
 						log.V(2).Info("Replacing image in OperatingSystemConfig file", "oldImage", match, "newImage", newImage)
+						originalImages[newImage] = match
 						updated = true
 						return newImage
 					}
@@ -93,9 +138,31 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 		}
 	}
 
+	maps.Copy(keptImages, originalImages)
+	if err := image.SetOriginalImages(osc, keptImages); err != nil {
+		return err
+	}
+
+	// The OperatingSystemConfig is updated with the original images on every reconciliation of the shoot. An event is
+	// only recorded if the rewritten images differ from the ones of the existing object.
+	if len(originalImages) > 0 && (old == nil || old.GetAnnotations()[image.AnnotationOriginalImages] != osc.Annotations[image.AnnotationOriginalImages]) {
+		event.RecordForNamespace(ctx, log, m.client, m.recorder, osc.Namespace, event.ReasonImagesRewritten, event.ActionMutate,
+			"Rewrote images of %s OperatingSystemConfig %s: %s", osc.Spec.Purpose, osc.Name, describeRewrites(originalImages))
+	}
+
 	return nil
 }
 
+// describeRewrites returns a sorted list of the rewrites, e.g. 'a:1 -> b:1, c:2 -> d:2'.
+func describeRewrites(originalImages map[string]string) string {
+	rewrites := make([]string, 0, len(originalImages))
+	for newImage, oldImage := range originalImages {
+		rewrites = append(rewrites, oldImage+" -> "+newImage)
+	}
+	slices.Sort(rewrites)
+	return strings.Join(rewrites, ", ")
+}
+
 func readData(fileContent *extensionsv1alpha1.FileContentInline) (string, error) {
 	if fileContent.Encoding == string(extensionsv1alpha1.B64FileCodecID) {
 		decodedData, err := gardenerutils.DecodeBase64(fileContent.Data)
@@ -116,9 +183,10 @@ func writeData(fileContent *extensionsv1alpha1.FileContentInline, data string) {
 }
 
 // NewMutator creates a new Mutator instance.
-func NewMutator(client client.Client, config *v1alpha1.Configuration) extensionswebhook.Mutator {
+func NewMutator(client client.Client, recorder events.EventRecorder, config *configutils.Store) extensionswebhook.Mutator {
 	return &mutator{
-		client: client,
-		config: image.NewImageConfiguration(config),
+		client:   client,
+		recorder: recorder,
+		config:   config,
 	}
 }
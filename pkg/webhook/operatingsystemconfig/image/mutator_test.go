@@ -12,13 +12,17 @@ import (
 	gardenerutils "github.com/gardener/gardener/pkg/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	. "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/operatingsystemconfig/image"
 )
 
@@ -27,8 +31,9 @@ var _ = Describe("Mutator", func() {
 		ctx        context.Context
 		fakeClient client.Client
 
-		config  *v1alpha1.Configuration
-		mutator extensionswebhook.Mutator
+		recorder *events.FakeRecorder
+		config   *v1alpha1.Configuration
+		mutator  extensionswebhook.Mutator
 
 		namespace           string
 		cluster             *extensionsv1alpha1.Cluster
@@ -68,7 +73,8 @@ var _ = Describe("Mutator", func() {
 			},
 		}
 
-		mutator = NewMutator(fakeClient, config)
+		recorder = events.NewFakeRecorder(10)
+		mutator = NewMutator(fakeClient, recorder, configutils.NewStore(config))
 
 		namespace = "shoot--test--local"
 
@@ -165,6 +171,18 @@ var _ = Describe("Mutator", func() {
 				))
 
 				Expect(osc.Spec.CRIConfig.Containerd.SandboxImage).To(Equal("sandbox-image:latest"))
+				Expect(osc.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+					`{"registry.north.local/replicas/node-agent:latest":"gardener.cloud/gardener-project/node-agent:latest"}`))
+				Expect(recorder.Events).To(Receive(Equal("Normal ImagesRewritten Rewrote images of provision OperatingSystemConfig test-osc: " +
+					"gardener.cloud/gardener-project/node-agent:latest -> registry.north.local/replicas/node-agent:latest")))
+			})
+
+			It("should not count strings of the file content which match no source as lookup misses", func() {
+				osc.Spec.Files[0].Content.Inline.Data = gardenerutils.EncodeBase64([]byte("listen on localhost:8080 and pull gardener.cloud/gardener-project/node-agent:latest"))
+				misses := testutil.ToFloat64(metrics.LookupMisses.WithLabelValues(Name, "local", "north"))
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+				Expect(testutil.ToFloat64(metrics.LookupMisses.WithLabelValues(Name, "local", "north"))).To(Equal(misses))
 			})
 		})
 
@@ -198,6 +216,37 @@ var _ = Describe("Mutator", func() {
 				))
 
 				Expect(osc.Spec.CRIConfig.Containerd.SandboxImage).To(Equal("local-north-sandbox-image:latest"))
+				Expect(osc.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+					`{"local-north-sandbox-image:latest":"sandbox-image:latest","registry.north.local/replicas/hyperkube:latest":"gardener.cloud/gardener-project/hyperkube:latest"}`))
+				Expect(recorder.Events).To(Receive(Equal("Normal ImagesRewritten Rewrote images of reconcile OperatingSystemConfig test-osc: " +
+					"gardener.cloud/gardener-project/hyperkube:latest -> registry.north.local/replicas/hyperkube:latest, sandbox-image:latest -> local-north-sandbox-image:latest")))
+			})
+
+			It("should only record an event if the rewritten images changed", func() {
+				oldOSC := osc.DeepCopy()
+				Expect(mutator.Mutate(ctx, oldOSC, nil)).To(Succeed())
+				Expect(recorder.Events).To(Receive())
+
+				// The webhook is invoked again for the same request.
+				Expect(mutator.Mutate(ctx, oldOSC, nil)).To(Succeed())
+				Expect(recorder.Events).NotTo(Receive())
+
+				// The OperatingSystemConfig is updated with the original images.
+				osc.Annotations = oldOSC.Annotations
+				Expect(mutator.Mutate(ctx, osc, oldOSC)).To(Succeed())
+				Expect(osc.Spec.CRIConfig.Containerd.SandboxImage).To(Equal("local-north-sandbox-image:latest"))
+				Expect(recorder.Events).NotTo(Receive())
+			})
+
+			It("should rebuild the original images when the webhook is invoked again", func() {
+				osc.Annotations = map[string]string{"image-rewriter.extensions.gardener.cloud/original-images": `{"removed-image:latest":"stale-image:latest"}`}
+
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+				Expect(mutator.Mutate(ctx, osc, nil)).To(Succeed())
+
+				Expect(osc.Spec.CRIConfig.Containerd.SandboxImage).To(Equal("local-north-sandbox-image:latest"))
+				Expect(osc.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+					`{"local-north-sandbox-image:latest":"sandbox-image:latest","registry.north.local/replicas/hyperkube:latest":"gardener.cloud/gardener-project/hyperkube:latest"}`))
 			})
 		})
 	})
@@ -5,16 +5,22 @@
 package pod
 
 import (
+	"fmt"
+
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	"github.com/gardener/gardener/extensions/pkg/webhook/shoot"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 )
 
 const (
@@ -25,22 +31,69 @@ const (
 var (
 	// DefaultAddOptions are the default AddOptions for AddToManager.
 	DefaultAddOptions = AddOptions{}
+
+	// workloadTypes are the workload resources whose pod templates can be rewritten, keyed by their kind.
+	workloadTypes = map[string]client.Object{
+		"Deployment":  &appsv1.Deployment{},
+		"DaemonSet":   &appsv1.DaemonSet{},
+		"StatefulSet": &appsv1.StatefulSet{},
+		"Job":         &batchv1.Job{},
+		"CronJob":     &batchv1.CronJob{},
+	}
 )
 
 // AddOptions are options to apply when adding the AWS shoot webhook to the manager.
 type AddOptions struct {
-	Config v1alpha1.Configuration
+	Config *configutils.Store
 }
 
 // AddToManager creates a webhook with the DefaultAddOptions.
 func AddToManager(mgr manager.Manager) (*extensionswebhook.Webhook, error) {
 	log.Log.Info("Adding webhook to manager")
 
-	return shoot.New(mgr, shoot.Args{
+	args := shoot.Args{
 		Types: []extensionswebhook.Type{
 			{Obj: &corev1.Pod{}},
+			{Obj: &corev1.Pod{}, Subresource: ptr.To("ephemeralcontainers")},
 		},
-		Mutator:       NewMutator(image.NewImageConfiguration(&DefaultAddOptions.Config)),
+		Mutator:       NewMutator(mgr.GetClient(), DefaultAddOptions.Config),
 		FailurePolicy: ptr.To(admissionregistrationv1.Ignore),
+	}
+	// The selectors and workload kinds are registered once, reloading the configuration doesn't change them.
+	podWebhook := DefaultAddOptions.Config.Get().PodWebhook
+	args.NamespaceSelector = NamespaceSelector(podWebhook)
+	if podWebhook != nil {
+		args.ObjectSelector = podWebhook.ObjectSelector
+
+		for _, kind := range podWebhook.WorkloadKinds {
+			obj, ok := workloadTypes[kind]
+			if !ok {
+				return nil, fmt.Errorf("unsupported workload kind %q", kind)
+			}
+			args.Types = append(args.Types, extensionswebhook.Type{Obj: obj})
+		}
+	}
+
+	return shoot.New(mgr, args)
+}
+
+// NamespaceSelector returns the namespace selector of the webhook for the given configuration. It selects the
+// 'kube-system' namespace unless the configuration specifies a namespace selector. Namespaces which are labelled with
+// SkipKey 'true' are never selected, hence the webhook isn't called for their pods at all.
+func NamespaceSelector(podWebhook *v1alpha1.PodWebhookConfiguration) *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{metav1.NamespaceSystem}},
+		},
+	}
+	if podWebhook != nil && podWebhook.NamespaceSelector != nil {
+		selector = podWebhook.NamespaceSelector.DeepCopy()
+	}
+
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      SkipKey,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{"true"},
 	})
+	return selector
 }
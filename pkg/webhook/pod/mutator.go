@@ -7,25 +7,39 @@ package pod
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/gardener/gardener-extension-image-rewriter/pkg/metrics"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
 )
 
+// SkipKey is the key of the annotation on pods and of the label on namespaces which disables the image rewrite.
+// The value of the pod annotation is either 'true' to skip all containers or a list of container names separated by
+// ','. Namespaces labelled with 'true' are excluded by the namespace selector of the webhook, see NamespaceSelector.
+const SkipKey = "image-rewriter.extensions.gardener.cloud/skip"
+
 type mutator struct {
-	config image.Configuration
+	client client.Client
+	config *configutils.Store
 }
 
 var _ extensionswebhook.WantsClusterObject = (*mutator)(nil)
 
 // NewMutator creates a new Mutator instance.
-func NewMutator(config image.Configuration) extensionswebhook.Mutator {
+func NewMutator(client client.Client, config *configutils.Store) extensionswebhook.Mutator {
 	return &mutator{
+		client: client,
 		config: config,
 	}
 }
@@ -34,9 +48,10 @@ func (m *mutator) WantsClusterObject() bool {
 	return true
 }
 
-// Mutate mutates the given Pod object by replacing the images of its containers if a replacement is defined.
-func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
-	log := logf.FromContext(ctx)
+// Mutate mutates the given Pod object by replacing the images of its init, regular and ephemeral containers if a
+// replacement is defined. For workload resources, the images of their pod templates are replaced.
+func (m *mutator) Mutate(ctx context.Context, new, old client.Object) error {
+	defer metrics.ObserveMutateDuration(Name, time.Now())
 
 	// Get Cluster Object from context
 	clusterValue := ctx.Value(extensionswebhook.ClusterObjectContextKey{})
@@ -49,24 +64,123 @@ func (m *mutator) Mutate(ctx context.Context, new, _ client.Object) error {
 		return fmt.Errorf("expected object to be of type *extensionscontroller.Cluster, got %T", new)
 	}
 
-	pod, ok := new.(*corev1.Pod)
-	if !ok {
-		return fmt.Errorf("expected new object to be of type *corev1.Pod, got %T", new)
+	switch obj := new.(type) {
+	case *corev1.Pod:
+		return m.mutatePod(ctx, cluster, obj)
+	case *appsv1.Deployment:
+		return m.mutatePodTemplate(ctx, cluster, obj, &obj.Spec.Template)
+	case *appsv1.DaemonSet:
+		return m.mutatePodTemplate(ctx, cluster, obj, &obj.Spec.Template)
+	case *appsv1.StatefulSet:
+		return m.mutatePodTemplate(ctx, cluster, obj, &obj.Spec.Template)
+	case *batchv1.Job:
+		// The pod template of a Job is immutable, a mutation of an update would be rejected.
+		if old != nil {
+			return nil
+		}
+		return m.mutatePodTemplate(ctx, cluster, obj, &obj.Spec.Template)
+	case *batchv1.CronJob:
+		return m.mutatePodTemplate(ctx, cluster, obj, &obj.Spec.JobTemplate.Spec.Template)
+	default:
+		return fmt.Errorf("expected new object to be a pod or a supported workload resource, got %T", new)
 	}
+}
 
-	for i, container := range pod.Spec.InitContainers {
-		if image := m.config.FindTargetImage(container.Image, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region); image != "" {
-			log.V(2).Info("Replacing container image", "oldImage", container.Image, "newImage", image)
-			pod.Spec.InitContainers[i].Image = image
-		}
+// mutatePodTemplate mutates the pod template of a workload resource the same way as the pods created from it.
+func (m *mutator) mutatePodTemplate(ctx context.Context, cluster *extensionscontroller.Cluster, obj client.Object, template *corev1.PodTemplateSpec) error {
+	pod := &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       template.Spec,
 	}
+	pod.Name, pod.Namespace = obj.GetName(), obj.GetNamespace()
 
-	for i, container := range pod.Spec.Containers {
-		if image := m.config.FindTargetImage(container.Image, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region); image != "" {
-			log.V(2).Info("Replacing container image", "oldImage", container.Image, "newImage", image)
-			pod.Spec.Containers[i].Image = image
-		}
+	if err := m.mutatePod(ctx, cluster, pod); err != nil {
+		return err
 	}
 
+	template.Spec = pod.Spec
+	template.Annotations = pod.Annotations
 	return nil
 }
+
+func (m *mutator) mutatePod(ctx context.Context, cluster *extensionscontroller.Cluster, pod *corev1.Pod) error {
+	log := logf.FromContext(ctx)
+
+	global := m.config.Get()
+	imageConfig, err := m.config.ImagesForNamespace(ctx, log, m.client, cluster.ObjectMeta.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get image rewriter configuration: %w", err)
+	}
+
+	skip := skippedContainers(pod)
+	if skip.all {
+		log.Info("Skipping image rewrite of pod", "pod", client.ObjectKeyFromObject(pod))
+		return nil
+	}
+
+	// The annotation is rebuilt on every mutation. Entries of earlier mutations are kept as long as the container still
+	// runs the image the original image is rewritten to, e.g. when a pod is updated after its creation.
+	recorded, err := image.GetOriginalImages(pod)
+	if err != nil {
+		log.Info("Ignoring original images annotation which cannot be decoded", "pod", client.ObjectKeyFromObject(pod), "error", err.Error())
+	}
+	// Container names are unique across all containers of a pod.
+	originalImages := map[string]string{}
+
+	rewrite := func(containerName string, containerImage *string) {
+		if skip.names.Has(containerName) {
+			log.Info("Skipping image rewrite of container", "pod", client.ObjectKeyFromObject(pod), "container", containerName)
+			return
+		}
+		result := imageConfig.Lookup(*containerImage, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region)
+		metrics.RecordLookup(Name, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region, global, result)
+		if result.Target != "" {
+			log.V(2).Info("Replacing container image", "oldImage", *containerImage, "newImage", result.Target)
+			originalImages[containerName] = *containerImage
+			*containerImage = result.Target
+			return
+		}
+		if original, ok := recorded[containerName]; ok && imageConfig.Lookup(original, cluster.Shoot.Spec.Provider.Type, cluster.Shoot.Spec.Region).Target == *containerImage {
+			originalImages[containerName] = original
+		}
+	}
+
+	for i := range pod.Spec.InitContainers {
+		rewrite(pod.Spec.InitContainers[i].Name, &pod.Spec.InitContainers[i].Image)
+	}
+	for i := range pod.Spec.Containers {
+		rewrite(pod.Spec.Containers[i].Name, &pod.Spec.Containers[i].Image)
+	}
+	// Ephemeral containers are added with the 'pods/ephemeralcontainers' subresource, e.g. by 'kubectl debug'. The
+	// subresource ignores changes of the metadata, hence the original images of ephemeral containers are not recorded.
+	for i := range pod.Spec.EphemeralContainers {
+		delete(recorded, pod.Spec.EphemeralContainers[i].Name)
+		rewrite(pod.Spec.EphemeralContainers[i].Name, &pod.Spec.EphemeralContainers[i].Image)
+		delete(originalImages, pod.Spec.EphemeralContainers[i].Name)
+	}
+
+	return image.SetOriginalImages(pod, originalImages)
+}
+
+type containerSkip struct {
+	// all is set if all containers are skipped.
+	all bool
+	// names contains the names of the skipped containers.
+	names sets.Set[string]
+}
+
+// skippedContainers returns the containers of the pod which must not be rewritten according to its annotation.
+func skippedContainers(pod *corev1.Pod) containerSkip {
+	value := strings.TrimSpace(pod.Annotations[SkipKey])
+	if value == "true" {
+		return containerSkip{all: true}
+	}
+
+	result := containerSkip{names: sets.New[string]()}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result.names.Insert(name)
+		}
+	}
+	return result
+}
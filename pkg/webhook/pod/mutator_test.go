@@ -10,24 +10,40 @@ import (
 	extensionscontroller "github.com/gardener/gardener/extensions/pkg/controller"
 	extensionswebhook "github.com/gardener/gardener/extensions/pkg/webhook"
 	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	extensionsv1alpha1 "github.com/gardener/gardener/pkg/apis/extensions/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
-	"github.com/gardener/gardener-extension-image-rewriter/pkg/utils/image"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 	. "github.com/gardener/gardener-extension-image-rewriter/pkg/webhook/pod"
 )
 
 var _ = Describe("Mutator", func() {
 	var (
-		config      *v1alpha1.Configuration
-		imageConfig image.Configuration
-		mutator     extensionswebhook.Mutator
+		ctx        context.Context
+		fakeClient client.Client
+
+		config  *v1alpha1.Configuration
+		mutator extensionswebhook.Mutator
+
+		namespace string
+		cluster   *extensionscontroller.Cluster
 	)
 
 	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(extensionsv1alpha1.AddToScheme(scheme)).To(Succeed())
+		fakeClient = fakeclient.NewClientBuilder().WithScheme(scheme).Build()
+
 		config = &v1alpha1.Configuration{
 			Overwrites: []v1alpha1.ImageOverwrite{
 				{
@@ -53,25 +69,29 @@ var _ = Describe("Mutator", func() {
 			},
 		}
 
-		imageConfig = image.NewImageConfiguration(config)
-		mutator = NewMutator(imageConfig)
-	})
+		mutator = NewMutator(fakeClient, configutils.NewStore(config))
 
-	Describe("#Mutate", func() {
-		It("should mutate all relevant container images", func() {
-			cluster := &extensionscontroller.Cluster{
-				Shoot: &gardencorev1beta1.Shoot{
-					Spec: gardencorev1beta1.ShootSpec{
-						Provider: gardencorev1beta1.Provider{
-							Type: "local",
-						},
-						Region: "north",
+		namespace = "shoot--test--local"
+
+		cluster = &extensionscontroller.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+			Shoot: &gardencorev1beta1.Shoot{
+				Spec: gardencorev1beta1.ShootSpec{
+					Provider: gardencorev1beta1.Provider{
+						Type: "local",
 					},
+					Region: "north",
 				},
-			}
+			},
+		}
 
-			ctx := context.WithValue(context.Background(), extensionswebhook.ClusterObjectContextKey{}, cluster)
+		ctx = context.WithValue(context.Background(), extensionswebhook.ClusterObjectContextKey{}, cluster)
+	})
 
+	Describe("#Mutate", func() {
+		It("should mutate all relevant container images", func() {
 			pod := &corev1.Pod{
 				Spec: corev1.PodSpec{
 					InitContainers: []corev1.Container{
@@ -95,5 +115,297 @@ var _ = Describe("Mutator", func() {
 				corev1.Container{Image: "another-image:latest"},
 			))
 		})
+
+		It("should mutate ephemeral container images", func() {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "target-image:latest"},
+					},
+					EphemeralContainers: []corev1.EphemeralContainer{
+						{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "source-image:latest"}},
+						{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "other", Image: "another-image:latest"}},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+			Expect(pod.Spec.EphemeralContainers[0].Image).To(Equal("target-image:latest"))
+			Expect(pod.Spec.EphemeralContainers[1].Image).To(Equal("another-image:latest"))
+			Expect(pod.Annotations).NotTo(HaveKey("image-rewriter.extensions.gardener.cloud/original-images"))
+		})
+
+		It("should record the original images of the rewritten containers", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"image-rewriter.extensions.gardener.cloud/original-images": `{"sidecar":"sidecar-image:latest"}`,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "init", Image: "init-source-image:latest"},
+					},
+					Containers: []corev1.Container{
+						{Name: "app", Image: "source-image:latest"},
+						{Name: "other", Image: "another-image:latest"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+				`{"app":"source-image:latest","init":"init-source-image:latest"}`))
+		})
+
+		It("should keep the original images of containers which were rewritten before", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"image-rewriter.extensions.gardener.cloud/original-images": `{"app":"source-image:latest","other":"removed-image:latest"}`,
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "init", Image: "init-source-image:latest"},
+					},
+					Containers: []corev1.Container{
+						{Name: "app", Image: "target-image:latest"},
+						{Name: "other", Image: "another-image:latest"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+				`{"app":"source-image:latest","init":"init-source-image:latest"}`))
+		})
+
+		It("should remove the annotation if no image is rewritten anymore", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"image-rewriter.extensions.gardener.cloud/original-images": `{"app":"removed-image:latest"}`,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "another-image:latest"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Annotations).NotTo(HaveKey("image-rewriter.extensions.gardener.cloud/original-images"))
+		})
+
+		It("should not record original images if nothing was rewritten", func() {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "other", Image: "another-image:latest"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Annotations).To(BeEmpty())
+		})
+
+		It("should use the overwrites of the extension provider config", func() {
+			Expect(fakeClient.Create(ctx, &extensionsv1alpha1.Extension{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "image-rewriter",
+					Namespace: namespace,
+				},
+				Spec: extensionsv1alpha1.ExtensionSpec{
+					DefaultSpec: extensionsv1alpha1.DefaultSpec{
+						Type: "image-rewriter",
+						ProviderConfig: &runtime.RawExtension{Raw: []byte(`{
+  "apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1",
+  "kind": "ImageRewriterConfig",
+  "overwrites": [{
+    "source": {"image": "source-image:latest"},
+    "targets": [{"image": "shoot-target-image:latest", "provider": "local"}]
+  }, {
+    "source": {"image": "another-image:latest"},
+    "targets": [{"image": "another-shoot-image:latest", "provider": "local", "regions": ["north"]}]
+  }]
+}`)},
+					},
+				},
+			})).To(Succeed())
+
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Image: "init-source-image:latest"},
+					},
+					Containers: []corev1.Container{
+						{Image: "source-image:latest"},
+						{Image: "another-image:latest"},
+					},
+				},
+			}
+
+			Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+			Expect(pod.Spec.InitContainers).To(ConsistOf(
+				corev1.Container{Image: "init-target-image:latest"},
+			))
+			Expect(pod.Spec.Containers).To(ConsistOf(
+				corev1.Container{Image: "shoot-target-image:latest"},
+				corev1.Container{Image: "another-shoot-image:latest"},
+			))
+		})
+
+		Context("workload resources", func() {
+			var template corev1.PodTemplateSpec
+
+			BeforeEach(func() {
+				template = corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"app": "platform"},
+					},
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{
+							{Name: "init", Image: "init-source-image:latest"},
+						},
+						Containers: []corev1.Container{
+							{Name: "app", Image: "source-image:latest"},
+							{Name: "other", Image: "another-image:latest"},
+						},
+					},
+				}
+			})
+
+			It("should mutate the pod template of a deployment", func() {
+				deployment := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "platform-system"},
+					Spec:       appsv1.DeploymentSpec{Template: template},
+				}
+
+				Expect(mutator.Mutate(ctx, deployment, nil)).To(Succeed())
+				Expect(deployment.Spec.Template.Labels).To(Equal(map[string]string{"app": "platform"}))
+				Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue("image-rewriter.extensions.gardener.cloud/original-images",
+					`{"app":"source-image:latest","init":"init-source-image:latest"}`))
+				Expect(deployment.Spec.Template.Spec.InitContainers[0].Image).To(Equal("init-target-image:latest"))
+				Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+				Expect(deployment.Spec.Template.Spec.Containers[1].Image).To(Equal("another-image:latest"))
+				Expect(deployment.Annotations).To(BeEmpty())
+			})
+
+			It("should mutate the pod template of a cron job", func() {
+				cronJob := &batchv1.CronJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "platform-system"},
+					Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+						Spec: batchv1.JobSpec{Template: template},
+					}},
+				}
+
+				Expect(mutator.Mutate(ctx, cronJob, nil)).To(Succeed())
+				Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+			})
+
+			It("should mutate the pod template of a job only when it is created", func() {
+				job := &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "platform-system"},
+					Spec:       batchv1.JobSpec{Template: template},
+				}
+				oldJob := job.DeepCopy()
+
+				Expect(mutator.Mutate(ctx, job, oldJob)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("source-image:latest"))
+
+				Expect(mutator.Mutate(ctx, job, nil)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+			})
+
+			It("should honour the opt-out annotation of the pod template", func() {
+				metav1.SetMetaDataAnnotation(&template.ObjectMeta, SkipKey, "app")
+				daemonSet := &appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "platform-system"},
+					Spec:       appsv1.DaemonSetSpec{Template: template},
+				}
+
+				Expect(mutator.Mutate(ctx, daemonSet, nil)).To(Succeed())
+				Expect(daemonSet.Spec.Template.Spec.InitContainers[0].Image).To(Equal("init-target-image:latest"))
+				Expect(daemonSet.Spec.Template.Spec.Containers[0].Image).To(Equal("source-image:latest"))
+			})
+
+			It("should fail for unsupported objects", func() {
+				Expect(mutator.Mutate(ctx, &appsv1.ReplicaSet{}, nil)).To(MatchError(ContainSubstring("expected new object to be a pod or a supported workload resource")))
+			})
+		})
+
+		Context("opt-out", func() {
+			var pod *corev1.Pod
+
+			BeforeEach(func() {
+				pod = &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "debug",
+						Namespace: "platform-system",
+					},
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{
+							{Name: "init", Image: "init-source-image:latest"},
+						},
+						Containers: []corev1.Container{
+							{Name: "app", Image: "source-image:latest"},
+							{Name: "debug", Image: "source-image:latest"},
+						},
+					},
+				}
+			})
+
+			It("should skip all containers if the pod is annotated", func() {
+				metav1.SetMetaDataAnnotation(&pod.ObjectMeta, SkipKey, "true")
+
+				Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+				Expect(pod.Spec.InitContainers[0].Image).To(Equal("init-source-image:latest"))
+				Expect(pod.Spec.Containers[0].Image).To(Equal("source-image:latest"))
+				Expect(pod.Spec.Containers[1].Image).To(Equal("source-image:latest"))
+			})
+
+			It("should skip the containers listed in the pod annotation", func() {
+				metav1.SetMetaDataAnnotation(&pod.ObjectMeta, SkipKey, "init, debug")
+
+				Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+				Expect(pod.Spec.InitContainers[0].Image).To(Equal("init-source-image:latest"))
+				Expect(pod.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+				Expect(pod.Spec.Containers[1].Image).To(Equal("source-image:latest"))
+			})
+
+			It("should rewrite all containers without annotation", func() {
+				Expect(mutator.Mutate(ctx, pod, nil)).To(Succeed())
+				Expect(pod.Spec.InitContainers[0].Image).To(Equal("init-target-image:latest"))
+				Expect(pod.Spec.Containers[0].Image).To(Equal("target-image:latest"))
+				Expect(pod.Spec.Containers[1].Image).To(Equal("target-image:latest"))
+			})
+		})
+	})
+})
+
+var _ = Describe("#NamespaceSelector", func() {
+	skipRequirement := metav1.LabelSelectorRequirement{Key: SkipKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"true"}}
+
+	It("should select the kube-system namespace by default", func() {
+		Expect(NamespaceSelector(nil)).To(Equal(&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"kube-system"}},
+			skipRequirement,
+		}}))
+	})
+
+	It("should exclude opted out namespaces from the configured selector", func() {
+		podWebhook := &v1alpha1.PodWebhookConfiguration{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+		}
+
+		Expect(NamespaceSelector(podWebhook)).To(Equal(&metav1.LabelSelector{
+			MatchLabels:      map[string]string{"team": "platform"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{skipRequirement},
+		}))
+		Expect(podWebhook.NamespaceSelector.MatchExpressions).To(BeEmpty())
 	})
 })
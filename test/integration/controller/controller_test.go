@@ -23,6 +23,7 @@ import (
 
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/apis/config/v1alpha1"
 	"github.com/gardener/gardener-extension-image-rewriter/pkg/controller"
+	configutils "github.com/gardener/gardener-extension-image-rewriter/pkg/utils/config"
 )
 
 var _ = Describe("Cluster controller test", func() {
@@ -90,7 +91,7 @@ var _ = Describe("Cluster controller test", func() {
 
 	Describe("Create the webhook configuration", Ordered, func() {
 		It("should add the configuration and controller to the manager", func() {
-			controller.DefaultAddOptions.Config = v1alpha1.Configuration{
+			controller.DefaultAddOptions.Config = configutils.NewStore(&v1alpha1.Configuration{
 				Overwrites: []v1alpha1.ImageOverwrite{
 					{
 						Source: v1alpha1.Image{Prefix: ptr.To("gardener.cloud/gardener-project")},
@@ -103,7 +104,7 @@ var _ = Describe("Cluster controller test", func() {
 						},
 					},
 				},
-			}
+			})
 
 			controller.DefaultAddOptions.ShootWebhookConfig = &atomic.Value{}
 			controller.DefaultAddOptions.ShootWebhookConfig.Store(&webhook.Configs{
@@ -142,6 +143,36 @@ var _ = Describe("Cluster controller test", func() {
 			Expect(managedResources.Items).To(BeEmpty(), "No managed resources should be created for the cluster with no overwrite configuration")
 		})
 
+		It("should add the webhook configuration because the provider config contains an overwrite", func() {
+			cluster.Spec.Shoot.Raw = rawShootNorth
+			extension.Spec.ProviderConfig = &runtime.RawExtension{Raw: []byte(`{
+"apiVersion": "image-rewriter.extensions.gardener.cloud/v1alpha1",
+"kind": "ImageRewriterConfig",
+"overwrites": [{
+  "source": {"prefix": "gardener.cloud/gardener-project"},
+  "targets": [{"prefix": "registry.north.local/gardener-project", "provider": "local", "regions": ["north"]}]
+}]
+}`)}
+			Expect(mgrClient.Create(ctx, cluster)).To(Succeed())
+			Expect(mgrClient.Create(ctx, extension)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(mgrClient.Delete(ctx, cluster)).To(Or(Succeed(), BeNotFoundError()))
+				Eventually(func() error {
+					return mgrClient.Get(ctx, client.ObjectKeyFromObject(cluster), &extensionsv1alpha1.Cluster{})
+				}).To(BeNotFoundError())
+
+				Expect(mgrClient.Delete(ctx, extension)).To(Or(Succeed(), BeNotFoundError()))
+				Eventually(func() error {
+					return mgrClient.Get(ctx, client.ObjectKeyFromObject(extension), &extensionsv1alpha1.Extension{})
+				}).To(BeNotFoundError())
+				verifyWebhookConfig(ctx, mgrClient, testRunID, false)
+			})
+
+			waitForExtensionReconciliation(extension)
+			verifyWebhookConfig(ctx, mgrClient, testRunID, true)
+		})
+
 		It("should add the webhook configuration to the cluster", func() {
 			cluster.Spec.Shoot.Raw = rawShootCentral
 			Expect(mgrClient.Create(ctx, cluster)).To(Succeed())